@@ -0,0 +1,87 @@
+package dump
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BytesFormat is the way []byte values are rendered
+type BytesFormat int
+
+const (
+	// BytesRaw renders []byte values as strings
+	BytesRaw BytesFormat = iota
+	// BytesHex renders []byte values as hexadecimal strings
+	BytesHex
+	// BytesBase64 renders []byte values as standard base64 strings
+	BytesBase64
+	// BytesAuto renders []byte values as strings if they are printable, as hexadecimal strings otherwise
+	BytesAuto
+	// BytesIndexed doesn't coerce []byte values, they are dumped as arrays of numbers
+	BytesIndexed
+)
+
+func (e *Encoder) formatBytes(b []byte) string {
+	switch e.BytesFormat {
+	case BytesHex:
+		return hex.EncodeToString(b)
+	case BytesBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case BytesAuto:
+		if !printable(b) {
+			return hex.EncodeToString(b)
+		}
+	}
+	return string(b)
+}
+
+// printable returns true if b is a valid UTF-8 string made of printable characters and spaces
+func printable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Encoder) isBinarySummarized(b []byte) bool {
+	return e.SummarizeBinary && len(b) > 0 && len(b) >= e.Limits.BinaryThreshold && !printable(b)
+}
+
+// binarySummary describes binary data with its size and its SHA-256 checksum
+func binarySummary(b []byte) string {
+	return fmt.Sprintf("<binary, %s, sha256=%s>", humanSize(len(b)), shortDigest(b))
+}
+
+// stringDigest describes a long string with its length and its SHA-256 checksum
+func stringDigest(s string) string {
+	return fmt.Sprintf("<string len=%d sha256=%s>", len(s), shortDigest([]byte(s)))
+}
+
+// shortDigest returns the beginning of the hexadecimal SHA-256 checksum of b
+func shortDigest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8]) + "…"
+}
+
+// humanSize formats a number of bytes, such as 2.3MB
+func humanSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
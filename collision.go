@@ -0,0 +1,76 @@
+package dump
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CollisionMode defines how the leaves dumped under the same key are detected
+type CollisionMode int
+
+const (
+	// CollisionsIgnore doesn't detect the collisions, the last leaf is kept
+	CollisionsIgnore CollisionMode = iota
+	// CollisionsExact detects the leaves of distinct paths dumped under the same key, such as
+	// two fields formatted the same way
+	CollisionsExact
+	// CollisionsCaseInsensitive also detects the keys differing only by case
+	CollisionsCaseInsensitive
+)
+
+// Collision describes two leaves dumped under the same key
+type Collision struct {
+	// Key and PreviousKey are the keys of the leaves, they only differ by case with
+	// CollisionsCaseInsensitive
+	Key, PreviousKey string
+	// Path and PreviousPath are the unformatted paths of the leaves
+	Path, PreviousPath string
+}
+
+// collisionEntry is the first leaf dumped under a key
+type collisionEntry struct {
+	key, path, id string
+}
+
+// checkCollision records the leaf dumped under the key k from roots. On collision, OnCollision is
+// called, or an error wrapping ErrKeyCollision is returned if it is nil.
+func (e *Encoder) checkCollision(w *dumpState, k string, roots []segment) error {
+	if w.collisions == nil {
+		w.collisions = map[string]collisionEntry{}
+	}
+	entry := collisionEntry{key: k, path: strings.Join(segmentNames(roots), "."), id: pathID(roots)}
+	norm := k
+	if e.Collisions == CollisionsCaseInsensitive {
+		norm = strings.ToLower(k)
+	}
+	prev, ok := w.collisions[norm]
+	if !ok {
+		w.collisions[norm] = entry
+		return nil
+	}
+	if prev.id == entry.id {
+		return nil
+	}
+	c := Collision{Key: k, PreviousKey: prev.key, Path: entry.path, PreviousPath: prev.path}
+	if e.OnCollision != nil {
+		e.OnCollision(c)
+		return nil
+	}
+	return fmt.Errorf("%w: %s and %s are both dumped as %s", ErrKeyCollision, c.PreviousPath, c.Path, c.Key)
+}
+
+// pathID identifies the path of a leaf, the map entries being identified by their number rather
+// than by their rendered key
+func pathID(roots []segment) string {
+	var sb strings.Builder
+	for _, s := range roots {
+		if s.kind == MapKeySegment {
+			sb.WriteString(strconv.Itoa(s.entry))
+		} else {
+			sb.WriteString(s.name)
+		}
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
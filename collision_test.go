@@ -0,0 +1,64 @@
+package dump_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestCollisions(t *testing.T) {
+	type T struct {
+		UserID string
+		UserId string
+		Name   string
+	}
+	a := T{UserID: "1", UserId: "2", Name: "foo"}
+
+	e := dump.NewDefaultEncoder()
+	e.Formatters = []dump.KeyFormatterFunc{dump.WithDefaultLowerCaseFormatter()}
+	_, err := e.ToMap(a)
+	require.NoError(t, err)
+
+	e.Collisions = dump.CollisionsExact
+	_, err = e.ToMap(a)
+	assert.True(t, errors.Is(err, dump.ErrKeyCollision))
+	assert.EqualError(t, err, "key collision: T.UserID and T.UserId are both dumped as t.userid")
+
+	var collisions []dump.Collision
+	e.OnCollision = func(c dump.Collision) {
+		collisions = append(collisions, c)
+	}
+	_, err = e.ToMap(a)
+	require.NoError(t, err)
+	assert.Equal(t, []dump.Collision{{
+		Key:          "t.userid",
+		PreviousKey:  "t.userid",
+		Path:         "T.UserId",
+		PreviousPath: "T.UserID",
+	}}, collisions)
+
+	e = dump.NewDefaultEncoder()
+	e.Collisions = dump.CollisionsExact
+	_, err = e.ToMap(a)
+	require.NoError(t, err)
+
+	e.Collisions = dump.CollisionsCaseInsensitive
+	_, err = e.ToMap(a)
+	assert.True(t, errors.Is(err, dump.ErrKeyCollision))
+}
+
+func TestCollisionsMapKeys(t *testing.T) {
+	m := map[interface{}]string{1: "int", "1": "string"}
+
+	e := dump.NewDefaultEncoder()
+	e.Collisions = dump.CollisionsExact
+	_, err := e.ToMap(m)
+	assert.True(t, errors.Is(err, dump.ErrKeyCollision))
+
+	_, err = e.FlattenMap(map[string]interface{}{"m": map[interface{}]interface{}{1: "int", "1": "string"}})
+	assert.True(t, errors.Is(err, dump.ErrKeyCollision))
+}
@@ -0,0 +1,157 @@
+package dump
+
+// detailKind is the kind of a node of a detailed value
+type detailKind int
+
+const (
+	detailNone detailKind = iota
+	detailLeaf
+	detailObject
+	detailArray
+)
+
+// detailNode is a node of the value dumped under the key of a struct, an array or a map with the
+// ExtraFields.DetailedStruct, DetailedArray and DetailedMap options
+type detailNode struct {
+	kind   detailKind
+	value  interface{}
+	fields map[string]*detailNode
+	elems  []*detailNode
+}
+
+// detail builds the detailed value of a container from the leaves dumped under it, once they
+// have been filtered, masked and redacted, so that it holds nothing which is not dumped as a leaf
+type detail struct {
+	// base is the length of the expanded path of the container
+	base int
+	root *detailNode
+}
+
+// beginDetail starts collecting the leaves dumped under roots
+func (w *dumpState) beginDetail(roots []segment, kind detailKind) {
+	root := &detailNode{}
+	root.setKind(kind)
+	w.details = append(w.details, &detail{base: len(expandSegments(roots)), root: root})
+}
+
+// endDetail stops collecting the leaves of the last container and returns its detailed value
+func (w *dumpState) endDetail() interface{} {
+	d := w.details[len(w.details)-1]
+	w.details = w.details[:len(w.details)-1]
+	return d.root.interfaceValue()
+}
+
+// container records that a struct, an array or a map is dumped under roots, so that it is part of
+// the detailed values even if it is empty
+func (w *dumpState) container(roots []segment, kind detailKind) {
+	if w.onContainer != nil {
+		w.onContainer(roots, kind)
+	}
+	if len(w.details) == 0 {
+		return
+	}
+	path := expandSegments(roots)
+	for _, d := range w.details {
+		if n := d.node(path); n != nil {
+			n.setKind(kind)
+		}
+	}
+}
+
+// collect adds a leaf to the detailed values being built. The containers win over the leaves dumped
+// under the same key, such as the String of a struct which is also expanded.
+func (w *dumpState) collect(roots []segment, v interface{}) {
+	if w.null && v == "" {
+		v = nil
+	}
+	path := expandSegments(roots)
+	for _, d := range w.details {
+		n := d.node(path)
+		if n == nil || n.kind == detailObject || n.kind == detailArray {
+			continue
+		}
+		n.kind = detailLeaf
+		n.value = v
+	}
+}
+
+// node returns the node of the expanded path, nil if it is not under the container
+func (d *detail) node(path []segment) *detailNode {
+	if len(path) < d.base {
+		return nil
+	}
+	n := d.root
+	for _, s := range path[d.base:] {
+		switch s.kind {
+		case TypeSegment, RootSegment, ExtraSegment:
+			continue
+		case IndexSegment:
+			n.setKind(detailArray)
+			for len(n.elems) <= s.index {
+				n.elems = append(n.elems, &detailNode{})
+			}
+			n = n.elems[s.index]
+		default:
+			n.setKind(detailObject)
+			c, ok := n.fields[s.name]
+			if !ok {
+				c = &detailNode{}
+				n.fields[s.name] = c
+			}
+			n = c
+		}
+	}
+	return n
+}
+
+// setKind turns the node into a container of the kind, dropping its previous content if it was a
+// leaf or another kind of container
+func (n *detailNode) setKind(kind detailKind) {
+	if n.kind == kind {
+		return
+	}
+	*n = detailNode{kind: kind}
+	if kind == detailObject {
+		n.fields = map[string]*detailNode{}
+	}
+}
+
+func (n *detailNode) interfaceValue() interface{} {
+	switch n.kind {
+	case detailLeaf:
+		return n.value
+	case detailObject:
+		res := make(map[string]interface{}, len(n.fields))
+		for k, c := range n.fields {
+			res[k] = c.interfaceValue()
+		}
+		return res
+	case detailArray:
+		res := make([]interface{}, len(n.elems))
+		for i, c := range n.elems {
+			res[i] = c.interfaceValue()
+		}
+		return res
+	}
+	return nil
+}
+
+// expandSegments replaces the segments of the elements in JSON notation or named with the
+// IndexFormatter, such as "List[0]", with the segment of their array followed by a plain index
+// segment
+func expandSegments(roots []segment) []segment {
+	var res []segment
+	for _, s := range roots {
+		res = append(res, expandSegment(s)...)
+	}
+	return res
+}
+
+func expandSegment(s segment) []segment {
+	if s.parent == nil {
+		return []segment{s}
+	}
+	p := *s.parent
+	s.parent = nil
+	return append(expandSegment(p), s)
+}
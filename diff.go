@@ -0,0 +1,79 @@
+package dump
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorReset = "\x1b[0m"
+)
+
+// Change is the difference of a key between two dumps. Old is empty if the key has been added,
+// New is empty if it has been removed.
+type Change struct {
+	Key     string
+	Old     string
+	New     string
+	Added   bool
+	Removed bool
+}
+
+// Diff dumps a and b with the options and returns the changed keys, sorted by key
+func Diff(a, b interface{}, opts ...Option) ([]Change, error) {
+	e := NewDefaultEncoder(opts...)
+	ma, err := e.ToStringMap(a)
+	if err != nil {
+		return nil, err
+	}
+	mb, err := e.ToStringMap(b)
+	if err != nil {
+		return nil, err
+	}
+	return diffMaps(ma, mb), nil
+}
+
+// diffMaps returns the changes between two dumps, sorted by key
+func diffMaps(ma, mb map[string]string) []Change {
+	var changes []Change
+	for k, v := range ma {
+		w, ok := mb[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: k, Old: v, Removed: true})
+		case v != w:
+			changes = append(changes, Change{Key: k, Old: v, New: w})
+		}
+	}
+	for k, w := range mb {
+		if _, ok := ma[k]; !ok {
+			changes = append(changes, Change{Key: k, New: w, Added: true})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// FormatDiff returns a colorized key-level diff between the dumps of a and b, the old values are
+// prefixed by "- " and the new ones by "+ ". It returns an empty string if the dumps are the same.
+func FormatDiff(a, b interface{}) string {
+	changes, err := Diff(a, b)
+	if err != nil {
+		return fmt.Sprintf("unable to diff: %v\n", err)
+	}
+	var sb strings.Builder
+	for _, c := range changes {
+		if !c.Added {
+			fmt.Fprintf(&sb, "%s- %s: %s%s\n", colorRed, c.Key, c.Old, colorReset)
+		}
+		if !c.Removed {
+			fmt.Fprintf(&sb, "%s+ %s: %s%s\n", colorGreen, c.Key, c.New, colorReset)
+		}
+	}
+	return sb.String()
+}
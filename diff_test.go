@@ -0,0 +1,32 @@
+package dump_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestDiff(t *testing.T) {
+	type Config struct {
+		Host  string
+		Port  int
+		Peers []string
+	}
+	a := Config{Host: "localhost", Port: 8080, Peers: []string{"a", "b"}}
+	b := Config{Host: "localhost", Port: 8081, Peers: []string{"a"}}
+
+	changes, err := dump.Diff(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, []dump.Change{
+		{Key: "Config.Peers.Peers1", Old: "b", Removed: true},
+		{Key: "Config.Port", Old: "8080", New: "8081"},
+	}, changes)
+
+	assert.Equal(t, "\x1b[31m- Config.Peers.Peers1: b\x1b[0m\n"+
+		"\x1b[31m- Config.Port: 8080\x1b[0m\n"+
+		"\x1b[32m+ Config.Port: 8081\x1b[0m\n", dump.FormatDiff(a, b))
+	assert.Empty(t, dump.FormatDiff(a, a))
+}
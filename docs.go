@@ -0,0 +1,104 @@
+package dump
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// DocFormat is the markup of the documentation written by Document
+type DocFormat int
+
+const (
+	// DocMarkdown writes a Markdown table
+	DocMarkdown DocFormat = iota
+	// DocAsciiDoc writes an AsciiDoc table
+	DocAsciiDoc
+)
+
+// docRow describes a key in the documentation
+type docRow struct {
+	key, typ, tag, value string
+}
+
+// Document writes to w the reference table of the keys of i, such as a configuration struct holding
+// its default values: each key comes with its Go type, its json tag and its value.
+func (e *Encoder) Document(w io.Writer, i interface{}, format DocFormat) (err error) {
+	if !e.DisableRecover {
+		defer recoverError(&err)
+	}
+	rows := map[string]*docRow{}
+	state := e.newDumpState()
+	state.emit = func(string, interface{}) bool { return true }
+	state.leaf = func(k string, v interface{}, roots []segment) {
+		row := &docRow{key: k, value: newlineEscaper.Replace(e.printValue(v))}
+		if v != nil {
+			row.typ = reflect.TypeOf(v).String()
+		}
+		if f := lastField(roots); f != nil {
+			row.tag = strings.Split(f.Tag.Get("json"), ",")[0]
+			if roots[len(roots)-1].kind == FieldSegment && f.Type.Kind() != reflect.Interface {
+				row.typ = f.Type.String()
+			}
+		}
+		rows[k] = row
+	}
+	if err := e.fdumpInterface(state, i, e.rootSegments(i)); err != nil {
+		return err
+	}
+	if state.err != nil {
+		return state.err
+	}
+
+	keys := make([]string, 0, len(rows))
+	for k := range rows {
+		keys = append(keys, k)
+	}
+	e.sortKeys(keys, nil)
+
+	switch format {
+	case DocAsciiDoc:
+		fmt.Fprintln(w, `[options="header"]`)
+		fmt.Fprintln(w, "|===")
+		fmt.Fprintln(w, "|Key |Type |JSON |Value")
+		for _, k := range keys {
+			r := rows[k]
+			fmt.Fprintf(w, "|%s |%s |%s |%s\n", asciiDocCell(r.key), asciiDocCell(r.typ), asciiDocCell(r.tag), asciiDocCell(r.value))
+		}
+		_, err = fmt.Fprintln(w, "|===")
+	default:
+		fmt.Fprintln(w, "| Key | Type | JSON | Value |")
+		fmt.Fprintln(w, "| --- | --- | --- | --- |")
+		for _, k := range keys {
+			r := rows[k]
+			_, err = fmt.Fprintf(w, "| %s | %s | %s | %s |\n", markdownCode(r.key), markdownCode(r.typ), markdownCode(r.tag), markdownCell(r.value))
+		}
+	}
+	return err
+}
+
+// lastField returns the struct field of the last field or element of roots
+func lastField(roots []segment) *reflect.StructField {
+	for i := len(roots) - 1; i >= 0; i-- {
+		if roots[i].field != nil {
+			return roots[i].field
+		}
+	}
+	return nil
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
+
+func asciiDocCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
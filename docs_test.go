@@ -0,0 +1,51 @@
+package dump_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestDocument(t *testing.T) {
+	type Config struct {
+		Listen  string            `json:"listen"`
+		Timeout time.Duration     `json:"timeout,omitempty"`
+		Labels  map[string]string `json:"labels"`
+		Filter  string
+	}
+	defaults := Config{
+		Listen:  ":8080",
+		Timeout: 30 * time.Second,
+		Labels:  map[string]string{"env": "dev"},
+		Filter:  "a|b",
+	}
+
+	e := dump.NewDefaultEncoder()
+	e.DisableTypePrefix = true
+
+	out := new(bytes.Buffer)
+	require.NoError(t, e.Document(out, defaults, dump.DocMarkdown))
+	assert.Equal(t, "| Key | Type | JSON | Value |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| `Filter` | `string` |  | a\\|b |\n"+
+		"| `Labels.env` | `string` | `labels` | dev |\n"+
+		"| `Listen` | `string` | `listen` | :8080 |\n"+
+		"| `Timeout` | `time.Duration` | `timeout` | 30s |\n", out.String())
+
+	out.Reset()
+	require.NoError(t, e.Document(out, defaults, dump.DocAsciiDoc))
+	assert.Equal(t, `[options="header"]
+|===
+|Key |Type |JSON |Value
+|Filter |string | |a\|b
+|Labels.env |string |labels |dev
+|Listen |string |listen |:8080
+|Timeout |time.Duration |timeout |30s
+|===
+`, out.String())
+}
@@ -1,10 +1,49 @@
 package dump
 
 import (
+	"bytes"
 	"io"
 	"os"
+	"sync"
 )
 
+var (
+	defaultMu      sync.RWMutex
+	defaultEncoder *Encoder
+)
+
+// SetDefault sets the encoder whose configuration is used by the package-level functions, such as
+// Dump, Sdump or ToMap. The encoder is copied, nil restores the default configuration.
+func SetDefault(enc *Encoder) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if enc == nil {
+		defaultEncoder = nil
+		return
+	}
+	defaultEncoder = enc.Clone()
+}
+
+// newEncoder returns a copy of the default encoder over the writer, the formatters override its
+// formatters if they are not nil.
+func newEncoder(w io.Writer, formatters []KeyFormatterFunc) *Encoder {
+	defaultMu.RLock()
+	d := defaultEncoder
+	defaultMu.RUnlock()
+
+	var e *Encoder
+	if d == nil {
+		e = NewEncoder(w)
+	} else {
+		e = d.Clone()
+		e.writer = w
+	}
+	if formatters != nil {
+		e.Formatters = formatters
+	}
+	return e
+}
+
 // Dump displays the passed parameter properties to standard out such as complete types and all
 // pointer addresses used to indirect to the final value.
 // See Fdump if you would prefer dumping to an arbitrary io.Writer or Sdump to
@@ -15,48 +54,29 @@ func Dump(i interface{}, formatters ...KeyFormatterFunc) error {
 
 // Sdump returns a string with the passed arguments formatted exactly the same as Dump.
 func Sdump(i interface{}, formatters ...KeyFormatterFunc) (string, error) {
-	if formatters == nil {
-		formatters = []KeyFormatterFunc{WithDefaultFormatter()}
-	}
-	e := NewDefaultEncoder()
-	e.Formatters = formatters
-	return e.Sdump(i)
+	return newEncoder(new(bytes.Buffer), formatters).Sdump(i)
 }
 
 // Fdump formats and displays the passed arguments to io.Writer w. It formats exactly the same as Dump.
 func Fdump(w io.Writer, i interface{}, formatters ...KeyFormatterFunc) error {
-	if formatters == nil {
-		formatters = []KeyFormatterFunc{WithDefaultFormatter()}
-	}
-	e := NewEncoder(w)
-	e.Formatters = formatters
-	return e.Fdump(i)
+	return newEncoder(w, formatters).Fdump(i)
 }
 
 // ToMap dumps argument as a map[string]interface{}
 func ToMap(i interface{}, formatters ...KeyFormatterFunc) (map[string]interface{}, error) {
-	if formatters == nil {
-		formatters = []KeyFormatterFunc{WithDefaultFormatter()}
-	}
-	e := NewDefaultEncoder()
-	e.Formatters = formatters
-	return e.ToMap(i)
+	return newEncoder(new(bytes.Buffer), formatters).ToMap(i)
 }
 
 // ToStringMap formats the argument as a map[string]string. It formats exactly the same as Dump.
 func ToStringMap(i interface{}, formatters ...KeyFormatterFunc) (map[string]string, error) {
-	if formatters == nil {
-		formatters = []KeyFormatterFunc{WithDefaultFormatter()}
-	}
-	e := NewDefaultEncoder()
-	e.Formatters = formatters
-	return e.ToStringMap(i)
+	return newEncoder(new(bytes.Buffer), formatters).ToStringMap(i)
 }
 
 // MustSdump is a helper that wraps a call to a function returning (string, error)
-// and panics if the error is non-nil.
+// and panics if the error is non-nil. It is meant for tests and debug logging, not for
+// production paths.
 func MustSdump(i interface{}, formatters ...KeyFormatterFunc) string {
-	enc := NewDefaultEncoder()
+	enc := newEncoder(new(bytes.Buffer), nil)
 	enc.Formatters = formatters
 	s, err := enc.Sdump(i)
 	if err != nil {
@@ -64,3 +84,23 @@ func MustSdump(i interface{}, formatters ...KeyFormatterFunc) string {
 	}
 	return s
 }
+
+// MustToMap is like ToMap but panics if the argument cannot be dumped. It is meant for tests and
+// debug logging, not for production paths.
+func MustToMap(i interface{}, formatters ...KeyFormatterFunc) map[string]interface{} {
+	m, err := ToMap(i, formatters...)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// MustToStringMap is like ToStringMap but panics if the argument cannot be dumped. It is meant for
+// tests and debug logging, not for production paths.
+func MustToStringMap(i interface{}, formatters ...KeyFormatterFunc) map[string]string {
+	m, err := ToStringMap(i, formatters...)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
@@ -0,0 +1,57 @@
+// Package dumptest compares the dumps of values against golden files.
+//
+// The golden files are stored in the testdata directory of the package under test. Run the
+// tests with the DUMPTEST_UPDATE environment variable set to write them:
+//
+//	DUMPTEST_UPDATE=1 go test ./...
+//
+// No flag is registered, so that the package can be imported by tests defining their own -update
+// flag. They can set Update from it:
+//
+//	dumptest.Update = *update
+package dumptest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsamin/go-dump"
+)
+
+// UpdateEnv is the environment variable which, if not empty, sets Update
+const UpdateEnv = "DUMPTEST_UPDATE"
+
+// Update writes the golden files instead of comparing the dumps with them
+var Update = os.Getenv(UpdateEnv) != ""
+
+// Golden compares the canonical dump of value with the golden file testdata/<name>.golden, the
+// test fails if they differ. The golden file is written instead if Update is true. Options
+// are applied after dump.WithCanonicalOutput.
+func Golden(t testing.TB, name string, value interface{}, opts ...dump.Option) {
+	t.Helper()
+	e := dump.NewDefaultEncoder(append([]dump.Option{dump.WithCanonicalOutput()}, opts...)...)
+	got, err := e.Sdump(value)
+	if err != nil {
+		t.Fatalf("unable to dump %s: %v", name, err)
+	}
+
+	path := filepath.Join("testdata", name+".golden")
+	if Update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("unable to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("unable to write %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read %s, run the tests with %s=1 to create it: %v", path, UpdateEnv, err)
+	}
+	if string(want) != got {
+		t.Errorf("dump of %s differs from %s:\n--- want\n%s+++ got\n%s", name, path, want, got)
+	}
+}
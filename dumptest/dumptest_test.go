@@ -0,0 +1,45 @@
+package dumptest_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fsamin/go-dump/dumptest"
+)
+
+func TestGolden(t *testing.T) {
+	type Server struct {
+		Host    string
+		Port    int
+		Timeout time.Duration
+		Started time.Time
+		Labels  map[string]string
+	}
+	s := Server{
+		Host:    "localhost",
+		Port:    8080,
+		Timeout: 30 * time.Second,
+		Started: time.Date(2020, 5, 1, 14, 30, 0, 0, time.UTC),
+		Labels:  map[string]string{"env": "prod", "app": "api"},
+	}
+	dumptest.Golden(t, "server", s)
+}
+
+func TestGoldenUpdate(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	dumptest.Update = true
+	defer func() { dumptest.Update = false }()
+	dumptest.Golden(t, "value", 42)
+
+	dumptest.Update = false
+	dumptest.Golden(t, "value", 42)
+}
@@ -3,17 +3,47 @@ package dump
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
-	"runtime"
+	"regexp"
 	"sort"
-	"strings"
+	"strconv"
+	"sync"
 )
 
-// Encoder ensures all options to dump an object
+// Encoder ensures all options to dump an object.
+//
+// Once configured, an Encoder is safe for concurrent use: each dump has its own state and Fdump
+// writes each dump at once, or by chunks of FlushEvery lines. The configuration fields and methods, such as RedactKeys or
+// RegisterDumper, must not be called concurrently with dumps, use Clone to derive an encoder.
 type Encoder struct {
-	Formatters  []KeyFormatterFunc
+	Formatters []KeyFormatterFunc
+	// ContextFormatters are applied on each key segment after the Formatters
+	ContextFormatters []KeyFormatterFuncV2
+	ValueFormatters   []ValueFormatterFunc
+	// DepthRange only dumps the leaves whose depth, the number of key segments without the type
+	// names, is between Min and Max, such as the top two levels of a large value with Max 2.
+	// Max is unbounded if 0.
+	DepthRange struct {
+		Min, Max int
+	}
+	// FieldFilter is called for each field of the dumped structs, the field is not dumped if it
+	// returns false, for instance to skip the fields tagged `internal`
+	FieldFilter func(parent reflect.Type, field reflect.StructField) bool
+	// Transform is called with the path, made of the unformatted key segments, and the value of each
+	// node before it is dumped. It returns the value to dump in place of the node, such as the
+	// summary of a database handle, or false to skip the node.
+	Transform func(path []string, v interface{}) (interface{}, bool)
+	// PostProcess is called with the leaves of each dump, once the value has been walked through,
+	// and returns the leaves returned by ToMap, ToStringMap, Fdump and Sdump, for instance to filter
+	// them or to add the hostname. It is not called by All and Stats.
+	PostProcess func(m map[string]interface{}) map[string]interface{}
+	// KindHooks normalize the leaves of each kind, such as RoundFloats or TrimStrings, before the
+	// ValueFormatters
+	KindHooks   map[reflect.Kind]KindHookFunc
 	ExtraFields struct {
 		Len            bool
 		Type           bool
@@ -22,34 +52,271 @@ type Encoder struct {
 		DetailedArray  bool
 		DeepJSON       bool
 		UseJSONTag     bool
+		// UseMapstructureTag computes keys from the `mapstructure` tags, as Viper does
+		UseMapstructureTag bool
+		// UseYAMLTag computes keys from the `yaml` tags
+		UseYAMLTag bool
+		// MapKeyType adds the type of the map keys under the __KeyType__ key of each entry
+		MapKeyType bool
+		// InterfaceType adds the dynamic type of the struct fields of interface types, under
+		// the __InterfaceType__ key
+		InterfaceType bool
+		// DeepYAML parses the string values as YAML documents and recurses into them if they are
+		// mappings or sequences in flow style, or spanning several lines or entries, so that
+		// "Error: connection refused" is kept as is. The DeepJSON limits apply.
+		DeepYAML bool
+		// DeepBase64JSON decodes the base64 string values and expands them like DeepJSON if the
+		// decoded value is a JSON array or object
+		DeepBase64JSON bool
 	}
 	ArrayJSONNotation bool
-	Separator         string
+	// IndexFormatter computes the key segment of each element of arrays and slices from the name
+	// of the array, empty for the dumped value itself, and the index of the element, such as
+	// "List#0" or "List(0)". It overrides ArrayJSONNotation.
+	IndexFormatter func(name string, index int) string
+	Separator      string
+	// DisableTypePrefix never inserts the type names in the keys, it overrides TypePrefix
 	DisableTypePrefix bool
-	Prefix            string
-	writer            io.Writer
+	// TypePrefix defines which struct values have their type name inserted in the keys
+	TypePrefix TypePrefixMode
+	Prefix     string
+	// RootName is the first key segment of the dumped values which are not structs, such as "Value"
+	// in "Value: 42". The elements of a dumped slice or map are nested under it.
+	RootName string
+	// Suffix is appended to every key, such as "value" in "T.A.value". The key patterns, such as
+	// RedactKeys or IncludeKeys, are matched against the keys without the Suffix.
+	Suffix string
+	// FormatSuffix applies the Formatters to the Suffix, as to the other key segments
+	FormatSuffix bool
+	// KeyMappers compute the Viper keys returned by ViperKey and ViperKeys. If nil, the Prefix is
+	// stripped, the Separator replaced with dots and the keys are lowercased.
+	KeyMappers []KeyMapper
+	// DocumentHeader returns a line written by Fdump before each dump, such as DefaultDocumentHeader,
+	// so that the dumps written to the same writer can be told apart. No line is written if it
+	// returns an empty string.
+	DocumentHeader func(i interface{}) string
+	// Progress is called every ProgressEvery dumped keys, DefaultProgressEvery if 0, to report the
+	// progress of the dumps of very large values
+	Progress      func(p Progress)
+	ProgressEvery int
+	// LineFunc is called by Fdump with the key and the value of each line before it is written. The
+	// line is not written if it returns ErrSkipLine, and Fdump stops if it returns another error.
+	// Use io.Discard as writer to only call LineFunc.
+	LineFunc func(key, value string) error
+	// FlushEvery writes and flushes the output of Fdump every FlushEvery lines, if positive, so that
+	// partial dumps are visible on buffered or network writers. The lines are then written as the
+	// leaves are dumped, in the order of the struct fields and of the map keys, unless the Traversal
+	// is breadth-first or PostProcess is set. The lines skipped by LineFunc are not counted.
+	FlushEvery int
+	// KeyOrder is the order of the keys written by Fdump and Sdump, KeyOrderNatural by default
+	KeyOrder KeyOrder
+	// Collisions detects the leaves dumped under the same key, such as fields whose names differ only
+	// by case once lowercased
+	Collisions CollisionMode
+	// OnCollision is called for each collision detected, if nil the dump returns an error wrapping
+	// ErrKeyCollision
+	OnCollision func(c Collision)
+	// Traversal is the order in which the leaves are yielded by All and written by Fdump and Sdump
+	Traversal Traversal
+	// MapKeySeparator defines how the map keys containing the Separator are handled
+	MapKeySeparator MapKeySeparatorMode
+	// EmptyMapKeys defines how the map entries whose key is rendered as an empty string are handled
+	EmptyMapKeys EmptyMapKeysMode
+	// AnonymousTypeName is the type prefix of the anonymous structs, they have no type prefix if empty
+	AnonymousTypeName string
+	// TagPriority is the ordered list of struct tags used to compute the key of the fields,
+	// such as []string{"dump", "json", "yaml"}. It overrides the ExtraFields.Use*Tag options.
+	TagPriority []string
+	// KeyTag is the name of a custom struct tag, such as "bson" or "env", used to compute the key
+	// of the fields. It takes precedence over the ExtraFields.Use*Tag options.
+	KeyTag string
+	// TimeFormat is the layout used to render time.Time values as a single leaf
+	TimeFormat string
+	// TimeUTC converts time.Time values to UTC before rendering them
+	TimeUTC bool
+	// UnixTime renders time.Time values as Unix timestamps, it takes precedence over TimeFormat
+	UnixTime UnixTimeFormat
+	// DurationFormat defines how time.Duration values are rendered
+	DurationFormat DurationFormat
+	// BigFloatPrecision is the number of significant digits used to render big.Float values,
+	// 0 uses big.Float.String and a negative value renders the shortest exact representation
+	BigFloatPrecision int
+	// BytesFormat defines how []byte values are rendered
+	BytesFormat BytesFormat
+	// SummarizeBinary replaces binary []byte and string values, at least Limits.BinaryThreshold bytes long,
+	// with a summary such as <binary, 2.3MB, sha256=...>
+	SummarizeBinary bool
+	// QuoteStrings renders string values with strconv.Quote, so each value holds on a single line
+	QuoteStrings bool
+	// Multiline defines how values containing newlines are rendered
+	Multiline MultilineMode
+	// BoolFormat defines the strings used to render bool values, "true" and "false" if empty
+	BoolFormat BoolFormat
+	// CanonicalNumbers renders float values in plain decimal notation, without exponent
+	CanonicalNumbers bool
+	// FloatPrecision is the fixed number of decimals of the float values, if positive
+	FloatPrecision int
+	// RedactPointers replaces the func, chan and unsafe.Pointer values, which are rendered as memory
+	// addresses, with PointerPlaceholder
+	RedactPointers bool
+	// NonFinitePlaceholder replaces the NaN and infinite float values, they are rendered as
+	// "NaN", "+Inf" and "-Inf" if empty
+	NonFinitePlaceholder string
+	// SummaryOnly dumps the nested slices, arrays and maps as a single leaf holding their type and
+	// length, such as <[]string len=3>, instead of their elements. The dumped value itself is expanded.
+	SummaryOnly bool
+	// DetectUUID renders [16]byte arrays as canonical UUID strings
+	DetectUUID bool
+	// PromoteEmbedded dumps the fields of embedded structs directly under their parent, as the
+	// `dump:"inline"` struct tag does
+	PromoteEmbedded bool
+	// Mask is the string replacing redacted values, DefaultMask if empty
+	Mask string
+	// ScrubSecrets masks the parts of string values which look like secrets, such as JWTs,
+	// AWS access keys, bearer tokens, PEM blocks or credit card numbers, whatever their key
+	ScrubSecrets bool
+	// MaskFunc is called on each leaf just before it is stored, it returns the value to store, or
+	// false to omit the leaf
+	MaskFunc func(key string, v interface{}) (interface{}, bool)
+	// Strict returns an error wrapping ErrMaxDepth or ErrUnsupportedType when a value is too deep or
+	// can't be dumped, instead of silently skipping or printing it
+	Strict bool
+	// DisableRecover lets the panics raised while dumping a value propagate with their original stack,
+	// instead of returning a PanicError. It is meant for development.
+	DisableRecover bool
+	// PreferStringer dumps the values implementing fmt.Stringer as a single leaf, the result of their
+	// String method, instead of expanding them
+	PreferStringer bool
+	// DisableStringer never calls the String method of the values, they are always expanded
+	DisableStringer bool
+	// Limits bounds the size of the dump
+	Limits struct {
+		// MaxDepth is the maximum depth of the dumped values, deeper values are not dumped
+		MaxDepth int
+		// MaxStringLength truncates longer string values
+		MaxStringLength int
+		// MaxBodySize is the maximum number of bytes captured from HTTP bodies, DefaultMaxBodySize if 0
+		MaxBodySize int64
+		// BinaryThreshold is the minimum size of the binary values summarized with SummarizeBinary
+		BinaryThreshold int
+		// DigestStringLength replaces the longer string values with their length and SHA-256
+		// checksum, such as <string len=48211 sha256=ab12…>, if positive
+		DigestStringLength int
+		// MaxJSONSize is the maximum size of the strings parsed with DeepJSON, DefaultMaxJSONSize
+		// if 0, unlimited if negative. Larger strings are dumped truncated to this size.
+		MaxJSONSize int
+		// MaxJSONDepth is the maximum nesting depth of the strings parsed with DeepJSON,
+		// DefaultMaxJSONDepth if 0, unlimited if negative
+		MaxJSONDepth int
+		// MaxMapEntries is the maximum number of entries dumped from a map, the first ones in the
+		// natural order of their keys. The number of entries is recorded under the __Len__ key.
+		MaxMapEntries int
+		// MaxSeqElements is the maximum number of elements dumped from iter.Seq and iter.Seq2 values,
+		// DefaultMaxSeqElements if 0
+		MaxSeqElements int
+	}
+
+	writer   io.Writer
+	writerMu *sync.Mutex
+	dumpers  map[reflect.Type]DumperFunc
+	// interfaceDumpers are the dumpers of the values implementing an interface
+	interfaceDumpers []interfaceDumper
+	skipTypes        map[reflect.Type]bool
+	opaques          []reflect.Type
+	redactions       []*regexp.Regexp
+	ignored          []*regexp.Regexp
+	included         []*regexp.Regexp
+	deepJSONKeys     []*regexp.Regexp
+	cache            *encoderCache
 }
 
 // NewDefaultEncoder instanciate a go-dump encoder
-func NewDefaultEncoder() *Encoder {
-	return NewEncoder(new(bytes.Buffer))
+func NewDefaultEncoder(opts ...Option) *Encoder {
+	return NewEncoder(new(bytes.Buffer), opts...)
 }
 
 // NewEncoder instanciate a go-dump encoder over the writer
-func NewEncoder(w io.Writer) *Encoder {
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
 	enc := &Encoder{
 		Formatters: []KeyFormatterFunc{
 			WithDefaultFormatter(),
 		},
 		Separator: ".",
 		writer:    w,
+		writerMu:  new(sync.Mutex),
+	}
+	enc.SkipTypes(defaultSkipTypes...)
+	for _, opt := range opts {
+		opt(enc)
 	}
 	return enc
 }
 
+// Clone returns a deep copy of the encoder, which can be configured without affecting the original
+// one, such as a shared base encoder tweaked per request.
+func (e *Encoder) Clone() *Encoder {
+	c := *e
+	c.writerMu = new(sync.Mutex)
+	c.cache = nil
+	c.Formatters = append([]KeyFormatterFunc(nil), e.Formatters...)
+	c.ContextFormatters = append([]KeyFormatterFuncV2(nil), e.ContextFormatters...)
+	c.ValueFormatters = append([]ValueFormatterFunc(nil), e.ValueFormatters...)
+	if e.KeyMappers != nil {
+		c.KeyMappers = append([]KeyMapper(nil), e.KeyMappers...)
+	}
+	c.TagPriority = append([]string(nil), e.TagPriority...)
+	c.opaques = append([]reflect.Type(nil), e.opaques...)
+	c.interfaceDumpers = append([]interfaceDumper(nil), e.interfaceDumpers...)
+	c.redactions = append([]*regexp.Regexp(nil), e.redactions...)
+	c.ignored = append([]*regexp.Regexp(nil), e.ignored...)
+	c.included = append([]*regexp.Regexp(nil), e.included...)
+	c.deepJSONKeys = append([]*regexp.Regexp(nil), e.deepJSONKeys...)
+	if e.KindHooks != nil {
+		c.KindHooks = make(map[reflect.Kind]KindHookFunc, len(e.KindHooks))
+		for k, fn := range e.KindHooks {
+			c.KindHooks[k] = fn
+		}
+	}
+	if e.dumpers != nil {
+		c.dumpers = make(map[reflect.Type]DumperFunc, len(e.dumpers))
+		for t, fn := range e.dumpers {
+			c.dumpers[t] = fn
+		}
+	}
+	if e.skipTypes != nil {
+		c.skipTypes = make(map[reflect.Type]bool, len(e.skipTypes))
+		for t, skip := range e.skipTypes {
+			c.skipTypes[t] = skip
+		}
+	}
+	return &c
+}
+
+// zeroWriterMu is the mutex of the writers of the encoders which are not created with NewEncoder
+var zeroWriterMu sync.Mutex
+
+// lockWriter locks the writer of the encoder and returns the function unlocking it
+func (e *Encoder) lockWriter() func() {
+	mu := e.writerMu
+	if mu == nil {
+		mu = &zeroWriterMu
+	}
+	mu.Lock()
+	return mu.Unlock
+}
+
+// SetWriter sets the io.Writer used by Fdump
+func (e *Encoder) SetWriter(w io.Writer) {
+	defer e.lockWriter()()
+	e.writer = w
+}
+
 // Fdump formats and displays the passed arguments to io.Writer w. It formats exactly the same as Dump.
 func (e *Encoder) Fdump(i interface{}) (err error) {
-	res, err := e.ToStringMap(i)
+	if e.FlushEvery > 0 && e.Traversal != TraversalBreadthFirst && e.PostProcess == nil {
+		return e.fdumpStream(i)
+	}
+	res, levels, err := e.toStringMap(i)
 	if err != nil {
 		return
 	}
@@ -58,24 +325,144 @@ func (e *Encoder) Fdump(i interface{}) (err error) {
 	for k := range res {
 		keys = append(keys, k)
 	}
-	sort.Strings(keys)
+	e.sortKeys(keys, levels)
+	buf := new(bytes.Buffer)
+	e.writeHeader(buf, i)
+
+	// The lines are buffered, so that LineFunc is called without holding the lock, and the dump is
+	// written at once, or by chunks of FlushEvery lines
+	var written int
 	for _, k := range keys {
-		var err error
-		if res[k] == "" {
-			_, err = fmt.Fprintf(e.writer, "%s:\n", k)
-		} else {
-			_, err = fmt.Fprintf(e.writer, "%s: %s\n", k, res[k])
-		}
+		ok, err := e.writeLine(buf, k, res[k])
 		if err != nil {
 			return err
 		}
+		if !ok {
+			continue
+		}
+		if written++; e.FlushEvery > 0 && written%e.FlushEvery == 0 {
+			if err := e.writeChunk(buf, true); err != nil {
+				return err
+			}
+		}
+	}
+	return e.writeChunk(buf, e.FlushEvery > 0)
+}
+
+// fdumpStream writes the lines of Fdump as the leaves are dumped, the writer is flushed every
+// FlushEvery written lines
+func (e *Encoder) fdumpStream(i interface{}) (err error) {
+	if !e.DisableRecover {
+		defer recoverError(&err)
+	}
+	buf := new(bytes.Buffer)
+	e.writeHeader(buf, i)
+
+	var written int
+	var werr error
+	seen := map[string]bool{}
+	w := e.newDumpState()
+	w.sorted = true
+	w.emit = func(k string, v interface{}) bool {
+		lines := map[string]string{}
+		e.multiline(lines, k, e.printValue(v))
+		keys := make([]string, 0, len(lines))
+		for lk := range lines {
+			keys = append(keys, lk)
+		}
+		e.sortKeys(keys, nil)
+		for _, lk := range keys {
+			if seen[lk] {
+				continue
+			}
+			seen[lk] = true
+			var ok bool
+			if ok, werr = e.writeLine(buf, lk, lines[lk]); werr != nil {
+				return false
+			}
+			if !ok {
+				continue
+			}
+			if written++; written%e.FlushEvery == 0 {
+				if werr = e.writeChunk(buf, true); werr != nil {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	err = e.fdumpInterface(w, i, e.rootSegments(i))
+	if werr != nil {
+		return werr
+	}
+	if err != nil {
+		return err
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return e.writeChunk(buf, true)
+}
+
+// writeHeader writes the DocumentHeader of i to buf, if any
+func (e *Encoder) writeHeader(buf *bytes.Buffer, i interface{}) {
+	if e.DocumentHeader != nil {
+		if h := e.DocumentHeader(i); h != "" {
+			fmt.Fprintln(buf, h)
+		}
+	}
+}
+
+// writeLine calls LineFunc and writes the line of the key k to buf, it returns false if the line
+// is skipped
+func (e *Encoder) writeLine(buf *bytes.Buffer, k, v string) (bool, error) {
+	if e.LineFunc != nil {
+		if err := e.LineFunc(k, v); errors.Is(err, ErrSkipLine) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+	if v == "" {
+		fmt.Fprintf(buf, "%s:\n", k)
+	} else {
+		fmt.Fprintf(buf, "%s: %s\n", k, e.indent(v))
+	}
+	return true, nil
+}
+
+// writeChunk writes buf to the writer of the encoder and resets it, the writer is then flushed if
+// flushWriter is true
+func (e *Encoder) writeChunk(buf *bytes.Buffer, flushWriter bool) error {
+	defer e.lockWriter()()
+	_, err := e.writer.Write(buf.Bytes())
+	buf.Reset()
+	if err != nil || !flushWriter {
+		return err
+	}
+	return flush(e.writer)
+}
+
+// Flush flushes the writer of the encoder if it is buffered, such as a *bufio.Writer, or if it
+// implements http.Flusher
+func (e *Encoder) Flush() error {
+	defer e.lockWriter()()
+	return flush(e.writer)
+}
+
+func flush(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
 	}
 	return nil
 }
 
 // Sdump returns a string with the passed arguments formatted exactly the same as Dump.
 func (e *Encoder) Sdump(i interface{}) (string, error) {
-	m, err := e.ToStringMap(i)
+	m, levels, err := e.toStringMap(i)
 	if err != nil {
 		return "", err
 	}
@@ -84,39 +471,185 @@ func (e *Encoder) Sdump(i interface{}) (string, error) {
 	for k := range m {
 		keys = append(keys, k)
 	}
-	sort.Strings(keys)
+	e.sortKeys(keys, levels)
 	for _, k := range keys {
-		res += fmt.Sprintf("%s: %s\n", k, m[k])
+		res += fmt.Sprintf("%s: %s\n", k, e.indent(m[k]))
 	}
 	return res, nil
 }
 
-func (e *Encoder) fdumpInterface(w map[string]interface{}, i interface{}, roots []string) error {
+// dumpState holds the state of a single dump
+type dumpState struct {
+	values map[string]interface{}
+	// visiting are the references being dumped, from the root to the current value
+	visiting map[reference]bool
+	// emit, if not nil, receives the leaves instead of values, the dump stops when it returns false
+	emit    func(k string, v interface{}) bool
+	stopped bool
+	// keys is the number of leaves stored or emitted
+	keys int
+	// maxDepth is the depth of the deepest leaf
+	maxDepth int
+	// depth is the depth of the value being dumped, it is only tracked for the breadth-first
+	// traversal if trackDepth is true
+	depth      int
+	trackDepth bool
+	// collisions are the first leaves dumped under each key, to detect the collisions, entries is
+	// the number of map entries walked through
+	collisions map[string]collisionEntry
+	entries    int
+	// err is the error which stopped the dump
+	err error
+	// details are the detailed values being built, from the outermost container
+	details []*detail
+	// null is true while the leaf of a nil pointer is dumped
+	null bool
+	// sorted walks the map entries in the natural order of their keys
+	sorted bool
+	// onContainer, if not nil, is called with the path of each struct, array and map
+	onContainer func(roots []segment, kind detailKind)
+	// leaf, if not nil, is called with the path of each leaf before it is stored or emitted
+	leaf func(k string, v interface{}, roots []segment)
+	// progress, if not nil, is called every progressEvery leaves
+	progress      func(p Progress)
+	progressEvery int
+}
+
+// errStopped is returned when the dump has been stopped by the emit function
+var errStopped = errors.New("dump stopped")
+
+// set stores or emits a leaf
+func (w *dumpState) set(k string, v interface{}) {
+	if w.stopped {
+		return
+	}
+	w.keys++
+	if w.progress != nil && w.keys%w.progressEvery == 0 {
+		w.progress(Progress{Keys: w.keys, Key: k})
+	}
+	if w.emit != nil {
+		w.stopped = !w.emit(k, v)
+		return
+	}
+	w.values[k] = v
+}
+
+type reference struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+func (e *Encoder) newDumpState() *dumpState {
+	w := &dumpState{
+		values:   map[string]interface{}{},
+		visiting: map[reference]bool{},
+	}
+	if e.Progress != nil {
+		w.progress = e.Progress
+		w.progressEvery = e.ProgressEvery
+		if w.progressEvery <= 0 {
+			w.progressEvery = DefaultProgressEvery
+		}
+	}
+	return w
+}
+
+// DefaultProgressEvery is the number of leaves between two calls of Progress if ProgressEvery is 0
+const DefaultProgressEvery = 10000
+
+// Progress reports the progress of a dump
+type Progress struct {
+	// Keys is the number of keys dumped so far
+	Keys int
+	// Key is the last dumped key
+	Key string
+}
+
+func (e *Encoder) fdumpInterface(w *dumpState, i interface{}, roots []segment) error {
+	if w.stopped {
+		if w.err != nil {
+			return w.err
+		}
+		return errStopped
+	}
+	if e.Transform != nil {
+		v, keep := e.Transform(segmentNames(roots), i)
+		if !keep {
+			return nil
+		}
+		i = v
+	}
+	if e.skipped(i) {
+		return nil
+	}
+	if e.DepthRange.Max > 0 && depth(roots) > e.DepthRange.Max {
+		return nil
+	}
+	if e.Limits.MaxDepth > 0 && depth(roots) > e.Limits.MaxDepth {
+		if e.Strict {
+			return e.newError(roots, reflect.TypeOf(i), ErrMaxDepth)
+		}
+		return nil
+	}
+	if w.trackDepth {
+		defer func(d int) { w.depth = d }(w.depth)
+		w.depth = depth(roots)
+	}
+	if rv := reflect.ValueOf(i); isReference(rv) {
+		ref := reference{ptr: rv.Pointer(), typ: rv.Type()}
+		if w.visiting[ref] {
+			return e.newError(roots, rv.Type(), ErrCycleDetected)
+		}
+		w.visiting[ref] = true
+		defer delete(w.visiting, ref)
+	}
 	f := valueFromInterface(i)
 	k := reflect.ValueOf(i).Kind()
 	if k == reflect.Ptr && reflect.ValueOf(i).IsNil() || !validAndNotEmpty(f) {
 		if len(roots) == 0 {
 			return nil
 		}
-		k := strings.Join(sliceFormat(roots, e.Formatters), e.Separator)
-		var prefix string
-		if e.Prefix != "" {
-			prefix = e.Prefix + e.Separator
-		}
-		w[prefix+k] = ""
+		w.null = true
+		e.fdumpLeaf(w, "", roots)
+		w.null = false
+		return nil
+	}
+	if e.opaque(reflect.TypeOf(i)) {
+		e.fdumpLeaf(w, e.printValue(i), roots)
 		return nil
 	}
+	if v, ok := e.leafValue(f); ok {
+		e.fdumpLeaf(w, v, roots)
+		return nil
+	}
+	if isSQLNull(f.Type()) {
+		return e.fdumpInterface(w, sqlNullValue(f), roots)
+	}
+	if stringer, ok := e.preferredStringer(i); ok {
+		croots := roots
+		if f.Kind() == reflect.Struct {
+			croots = e.structPrefix(roots, f.Type())
+		}
+		if len(croots) > 0 {
+			e.fdumpLeaf(w, safeString(stringer), croots)
+			return nil
+		}
+	}
+	if elems, ok := containerElements(f); ok {
+		return e.fdumpInterface(w, elems, roots)
+	}
+	if elems, ok := e.seqElements(f); ok {
+		if pairs, ok := elems.([]seqPair); ok {
+			return e.fdumpSeqPairs(w, pairs, roots)
+		}
+		return e.fdumpInterface(w, elems, roots)
+	}
 	switch f.Kind() {
 	case reflect.Struct:
 		if e.ExtraFields.Type {
-			nodeType := append(roots, "__Type__")
-			nodeTypeFormatted := strings.Join(sliceFormat(nodeType, e.Formatters), e.Separator)
-			w[nodeTypeFormatted] = f.Type().Name()
-		}
-		croots := roots
-		if len(roots) == 0 && !e.DisableTypePrefix {
-			croots = append(roots, f.Type().Name())
+			e.setExtra(w, roots, "__Type__", f.Type().Name())
 		}
+		croots := e.structPrefix(roots, f.Type())
 		if err := e.fdumpStruct(w, f, croots); err != nil {
 			return err
 		}
@@ -127,40 +660,202 @@ func (e *Encoder) fdumpInterface(w map[string]interface{}, i interface{}, roots
 		return nil
 	case reflect.Map:
 		if e.ExtraFields.Type {
-			nodeType := append(roots, "__Type__")
-			nodeTypeFormatted := strings.Join(sliceFormat(nodeType, e.Formatters), e.Separator)
-			w[nodeTypeFormatted] = "Map"
+			e.setExtra(w, roots, "__Type__", "Map")
 		}
 		if err := e.fDumpMap(w, i, roots); err != nil {
 			return err
 		}
 		return nil
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if e.Strict {
+			return e.newError(roots, f.Type(), ErrUnsupportedType)
+		}
+		e.fdumpLeaf(w, f.Interface(), roots)
 	default:
-		k := strings.Join(sliceFormat(roots, e.Formatters), e.Separator)
-		if e.ExtraFields.DeepJSON && (f.Kind() == reflect.String) {
-			if err := e.fDumpJSON(w, f.Interface().(string), roots, k); err != nil {
+		k := e.formatKey(roots)
+		if e.ExtraFields.DeepBase64JSON && f.Kind() == reflect.String {
+			if b, ok := decodeBase64JSON(f.String()); ok {
+				return e.fDumpJSON(w, string(b), roots, k)
+			}
+		}
+		if e.ExtraFields.DeepYAML && f.Kind() == reflect.String && !(e.deepJSON(roots) && isJSONContainer([]byte(f.String()))) {
+			if v, ok := e.parseYAML(f.String()); ok {
+				return e.fdumpInterface(w, v, roots)
+			}
+		}
+		if f.Kind() == reflect.String && e.deepJSON(roots) {
+			if err := e.fDumpJSON(w, f.String(), roots, k); err != nil {
 				return err
 			}
 		} else {
-			var prefix string
-			if e.Prefix != "" {
-				prefix = e.Prefix + e.Separator
-			}
-			w[prefix+k] = f.Interface()
+			e.fdumpLeaf(w, f.Interface(), roots)
 		}
 
 	}
 	return nil
 }
 
-func (e *Encoder) fDumpJSON(w map[string]interface{}, i string, roots []string, k string) error {
+// fullKey returns the key of the leaf dumped under roots, with the Prefix and the Suffix
+func (e *Encoder) fullKey(roots []segment) string {
+	return e.suffixed(e.prefixedKey(roots), roots)
+}
+
+// prefixedKey returns the key of the leaf dumped under roots with the Prefix but without the
+// Suffix, the key patterns such as RedactKeys are matched against it
+func (e *Encoder) prefixedKey(roots []segment) string {
+	k := e.leafKey(roots)
+	if e.Prefix != "" {
+		k = e.Prefix + e.Separator + k
+	}
+	return k
+}
+
+// suffixed appends the Suffix to the key k of the leaf dumped under roots
+func (e *Encoder) suffixed(k string, roots []segment) string {
+	if e.Suffix == "" {
+		return k
+	}
+	if e.FormatSuffix {
+		return k + e.Separator + format(e.Suffix, e.Formatters, len(roots))
+	}
+	return k + e.Separator + e.Suffix
+}
+
+// setExtra sets the extra field name, such as __Len__, of the node dumped under roots. As the
+// leaves, it is skipped if its key is out of the DepthRange or filtered out.
+func (e *Encoder) setExtra(w *dumpState, roots []segment, name string, v interface{}) {
+	croots := append(roots[:len(roots):len(roots)], segment{name: name, kind: ExtraSegment})
+	if !e.inDepthRange(croots) || e.filtered(e.prefixedKey(croots)) {
+		return
+	}
+	w.set(e.formatKey(croots), v)
+}
+
+// inDepthRange tells if the depth of roots is within the DepthRange
+func (e *Encoder) inDepthRange(roots []segment) bool {
+	d := depth(roots)
+	return d >= e.DepthRange.Min && (e.DepthRange.Max == 0 || d <= e.DepthRange.Max)
+}
+
+func (e *Encoder) fdumpLeaf(w *dumpState, v interface{}, roots []segment) {
+	if !e.inDepthRange(roots) {
+		return
+	}
+	k := e.prefixedKey(roots)
+	if e.filtered(k) {
+		return
+	}
+	if len(e.KindHooks) > 0 && v != nil {
+		rv := reflect.ValueOf(v)
+		if hook, ok := e.KindHooks[rv.Kind()]; ok {
+			v = ""
+			if rv = hook(rv); rv.IsValid() && rv.CanInterface() {
+				v = rv.Interface()
+			}
+		}
+	}
+	if len(e.ValueFormatters) > 0 {
+		rv := reflect.ValueOf(v)
+		for _, f := range e.ValueFormatters {
+			rv = f(k, rv)
+		}
+		v = ""
+		if rv.IsValid() && rv.CanInterface() {
+			v = rv.Interface()
+		}
+	}
+	if matchAny(e.redactions, k) {
+		v = e.maskValue(v, "")
+	}
+	if s, ok := v.(string); ok && e.SummarizeBinary && e.isBinarySummarized([]byte(s)) {
+		v = binarySummary([]byte(s))
+	}
+	if s, ok := v.(string); ok && e.Limits.DigestStringLength > 0 && len(s) > e.Limits.DigestStringLength {
+		v = stringDigest(s)
+	}
+	if s, ok := v.(string); ok && e.ScrubSecrets {
+		v = e.scrubSecrets(s)
+	}
+	if s, ok := v.(string); ok && e.Limits.MaxStringLength > 0 {
+		v = truncate(s, e.Limits.MaxStringLength)
+	}
+	if e.MaskFunc != nil {
+		var keep bool
+		if v, keep = e.MaskFunc(k, v); !keep {
+			return
+		}
+	}
+	if d := depth(roots); d > w.maxDepth {
+		w.maxDepth = d
+	}
+	k = e.suffixed(k, roots)
+	if e.Collisions != CollisionsIgnore {
+		if err := e.checkCollision(w, k, roots); err != nil {
+			w.err = err
+			w.stopped = true
+			return
+		}
+	}
+	if w.leaf != nil {
+		w.leaf(k, v, roots)
+	}
+	if w.trackDepth {
+		w.depth = depth(roots)
+	}
+	if len(w.details) > 0 {
+		w.collect(roots, v)
+	}
+	w.set(k, v)
+}
+
+// RedactKeys replaces the values of the leaves whose key matches one of the glob patterns, such
+// as "*password*", with the mask. Patterns are matched against the formatted keys, ignoring case.
+func (e *Encoder) RedactKeys(patterns ...string) {
+	for _, p := range patterns {
+		e.redactions = append(e.redactions, globToRegexp(p, true))
+	}
+}
+
+// DeepJSONKeys restricts ExtraFields.DeepJSON to the leaves whose key matches one of the glob
+// patterns, such as "*.Payload". Patterns are matched against the formatted keys, ignoring case.
+func (e *Encoder) DeepJSONKeys(patterns ...string) {
+	for _, p := range patterns {
+		e.deepJSONKeys = append(e.deepJSONKeys, globToRegexp(p, true))
+	}
+}
+
+// deepJSON returns true if the value at the path must be parsed with DeepJSON
+func (e *Encoder) deepJSON(roots []segment) bool {
+	if !e.ExtraFields.DeepJSON {
+		return false
+	}
+	if len(e.deepJSONKeys) == 0 {
+		return true
+	}
+	return matchAny(e.deepJSONKeys, e.prefixedKey(roots))
+}
+
+// RedactKeysRegexp replaces the values of the leaves whose key matches one of the regular expressions
+// with the mask.
+func (e *Encoder) RedactKeysRegexp(res ...*regexp.Regexp) {
+	e.redactions = append(e.redactions, res...)
+}
+
+func (e *Encoder) fDumpJSON(w *dumpState, i string, roots []segment, k string) error {
+	if !e.deepJSONAllowed(i) {
+		if maxSize := e.maxJSONSize(); maxSize > 0 && len(i) > maxSize {
+			i = truncate(i, maxSize)
+		}
+		e.fdumpLeaf(w, i, roots)
+		return nil
+	}
 	var value interface{}
 	bodyJSONArray := []interface{}{}
 	// Try to parse as a json array
-	if err := json.Unmarshal([]byte(i), &bodyJSONArray); err != nil {
+	if err := unmarshalJSON([]byte(i), &bodyJSONArray); err != nil {
 		//Try to parse as a map
 		bodyJSONMap := map[string]interface{}{}
-		if err2 := json.Unmarshal([]byte(i), &bodyJSONMap); err2 == nil {
+		if err2 := unmarshalJSON([]byte(i), &bodyJSONMap); err2 == nil {
 			value = bodyJSONMap
 		} else {
 			value = i
@@ -170,11 +865,7 @@ func (e *Encoder) fDumpJSON(w map[string]interface{}, i string, roots []string,
 	}
 
 	if value == i {
-		var prefix string
-		if e.Prefix != "" {
-			prefix = e.Prefix + e.Separator
-		}
-		w[prefix+k] = i
+		e.fdumpLeaf(w, i, roots)
 		return nil
 	}
 	if err := e.fdumpInterface(w, value, roots); err != nil {
@@ -183,19 +874,30 @@ func (e *Encoder) fDumpJSON(w map[string]interface{}, i string, roots []string,
 	return nil
 }
 
-func (e *Encoder) fDumpArray(w map[string]interface{}, i interface{}, roots []string) error {
+func (e *Encoder) fDumpArray(w *dumpState, i interface{}, roots []segment) error {
 	f := valueFromInterface(i)
-	if _, ok := f.Interface().([]byte); ok {
-		if err := e.fdumpInterface(w, string(f.Interface().([]byte)), roots); err != nil {
+	if raw, ok := f.Interface().(json.RawMessage); ok {
+		return e.fdumpInterface(w, string(raw), roots)
+	}
+	if f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Uint8 && e.deepJSON(roots) && isJSONContainer(f.Bytes()) {
+		return e.fDumpJSON(w, string(f.Bytes()), roots, "")
+	}
+	if b, ok := f.Interface().([]byte); ok && e.isBinarySummarized(b) {
+		e.fdumpLeaf(w, binarySummary(b), roots)
+		return nil
+	}
+	if b, ok := f.Interface().([]byte); ok && e.BytesFormat != BytesIndexed {
+		if err := e.fdumpInterface(w, e.formatBytes(b), roots); err != nil {
 			return err
 		}
 		return nil
 	}
+	if e.summarized(w, f, roots) {
+		return nil
+	}
 
 	if e.ExtraFields.Type {
-		nodeType := append(roots, "__Type__")
-		nodeTypeFormatted := strings.Join(sliceFormat(nodeType, e.Formatters), e.Separator)
-		w[nodeTypeFormatted] = "Array"
+		e.setExtra(w, roots, "__Type__", "Array")
 	}
 
 	v := reflect.ValueOf(i)
@@ -204,45 +906,22 @@ func (e *Encoder) fDumpArray(w map[string]interface{}, i interface{}, roots []st
 	}
 
 	if e.ExtraFields.Len {
-		nodeLen := append(roots, "__Len__")
-		nodeLenFormatted := strings.Join(sliceFormat(nodeLen, e.Formatters), e.Separator)
-		w[nodeLenFormatted] = v.Len()
+		e.setExtra(w, roots, "__Len__", v.Len())
 	}
 
-	if e.ExtraFields.DetailedArray && len(roots) > 0 {
-		structKey := strings.Join(sliceFormat(roots, e.Formatters), e.Separator)
-		w[structKey] = i
+	w.container(roots, detailArray)
+	detailed := e.ExtraFields.DetailedArray && len(roots) > 0
+	if detailed {
+		w.beginDetail(roots, detailArray)
 	}
 
 	for i := 0; i < v.Len(); i++ {
-		var l string
-		var croots []string
-		if len(roots) > 0 {
-			l = roots[len(roots)-1:][0]
-			if !e.ArrayJSONNotation {
-				croots = append(roots, fmt.Sprintf("%s%d", l, i))
-			} else {
-				var t = make([]string, len(roots)-1)
-				copy(t, roots[0:len(roots)-1])
-				croots = append(t, fmt.Sprintf("%s[%d]", l, i))
-			}
-		} else {
-			var skey = fmt.Sprintf("[%d]", i)
-			if !e.ArrayJSONNotation {
-				skey = fmt.Sprintf("%s%d", e.Prefix+l, i)
-			}
-			croots = append(roots, skey)
-		}
+		croots := e.indexRoots(roots, i)
 		f := v.Index(i)
 
-		stringer, ok := f.Interface().(fmt.Stringer)
-		if ok {
-			k := strings.Join(sliceFormat(croots, e.Formatters), e.Separator)
-			var prefix string
-			if e.Prefix != "" {
-				prefix = e.Prefix
-			}
-			w[prefix+k] = stringer.String()
+		stringer, ok := e.stringer(f.Interface())
+		if ok && !e.PreferStringer {
+			e.fdumpLeaf(w, safeString(stringer), croots)
 		}
 
 		if err := e.fdumpInterface(w, f.Interface(), croots); err != nil {
@@ -250,34 +929,111 @@ func (e *Encoder) fDumpArray(w map[string]interface{}, i interface{}, roots []st
 		}
 	}
 
+	if detailed {
+		e.fdumpLeaf(w, w.endDetail(), roots)
+	}
 	return nil
 }
 
-func (e *Encoder) fDumpMap(w map[string]interface{}, i interface{}, roots []string) error {
+// indexRoots returns the path of the element i of the array or slice dumped under roots
+func (e *Encoder) indexRoots(roots []segment, i int) []segment {
+	if e.IndexFormatter != nil {
+		if len(roots) == 0 {
+			return []segment{{name: e.IndexFormatter("", i), kind: IndexSegment, index: i}}
+		}
+		p := roots[len(roots)-1]
+		var t = make([]segment, len(roots)-1)
+		copy(t, roots[0:len(roots)-1])
+		return append(t, segment{name: e.IndexFormatter(p.name, i), kind: IndexSegment, index: i, field: p.field, parent: &p})
+	}
+	if len(roots) == 0 {
+		var skey = fmt.Sprintf("[%d]", i)
+		if !e.ArrayJSONNotation {
+			skey = fmt.Sprintf("%s%d", e.Prefix, i)
+		}
+		return append(roots, segment{name: skey, kind: IndexSegment, index: i})
+	}
+	l := roots[len(roots)-1].name
+	if !e.ArrayJSONNotation {
+		return append(roots[:len(roots):len(roots)], segment{name: fmt.Sprintf("%s%d", l, i), kind: IndexSegment, index: i})
+	}
+	p := roots[len(roots)-1]
+	var t = make([]segment, len(roots)-1)
+	copy(t, roots[0:len(roots)-1])
+	return append(t, segment{name: fmt.Sprintf("%s[%d]", l, i), kind: IndexSegment, index: i, field: p.field, parent: &p})
+}
+
+// summarized dumps the nested collection v as a single leaf, such as <[]string len=3>, with SummaryOnly
+func (e *Encoder) summarized(w *dumpState, v reflect.Value, roots []segment) bool {
+	if !e.SummaryOnly || len(roots) == 0 {
+		return false
+	}
+	e.fdumpLeaf(w, fmt.Sprintf("<%s len=%d>", v.Type(), v.Len()), roots)
+	return true
+}
+
+func (e *Encoder) fDumpMap(w *dumpState, i interface{}, roots []segment) error {
 	v := reflect.ValueOf(i)
+	if e.summarized(w, v, roots) {
+		return nil
+	}
 
-	keys := v.MapKeys()
-	var lenKeys int64
-	for _, k := range keys {
-		key := fmt.Sprintf("%v", k.Interface())
+	type entry struct {
+		k   reflect.Value
+		key string
+	}
+	var entries []entry
+	for _, k := range v.MapKeys() {
+		key := e.mapKey(k)
 		if key == "" {
-			continue
+			switch e.EmptyMapKeys {
+			case EmptyMapKeysPlaceholder:
+				key = EmptyMapKeyPlaceholder
+			case EmptyMapKeysError:
+				return e.newError(roots, k.Type(), ErrEmptyMapKey)
+			default:
+				continue
+			}
+		}
+		key, err := e.mapKeySegment(roots, key)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{k: k, key: key})
+	}
+	lenKeys := int64(len(entries))
+	truncated := e.Limits.MaxMapEntries > 0 && len(entries) > e.Limits.MaxMapEntries
+	if truncated || w.sorted {
+		sort.Slice(entries, func(i, j int) bool {
+			return naturalLess(entries[i].key, entries[j].key)
+		})
+	}
+	if truncated {
+		entries = entries[:e.Limits.MaxMapEntries]
+	}
+
+	w.container(roots, detailObject)
+	detailed := e.ExtraFields.DetailedMap && len(roots) != 0
+	if detailed {
+		w.beginDetail(roots, detailObject)
+	}
+	for _, en := range entries {
+		k, key := en.k, en.key
+		w.entries++
+		croots := append(roots, segment{name: key, kind: MapKeySegment, entry: w.entries})
+		if e.ExtraFields.MapKeyType {
+			e.setExtra(w, croots, "__KeyType__", k.Type().String())
 		}
-		lenKeys++
-		croots := append(roots, key)
 		value := v.MapIndex(k)
 
 		f := valueFromInterface(value.Interface())
 
-		if validAndNotEmpty(f) && f.Type().Kind() == reflect.Struct {
-			stringer, ok := value.Interface().(fmt.Stringer)
+		if _, preferred := e.preferredStringer(value.Interface()); !preferred && validAndNotEmpty(f) && f.Type().Kind() == reflect.Struct && !e.isLeaf(f) {
+			stringer, ok := e.stringer(value.Interface())
 			if ok {
-				structKey := strings.Join(sliceFormat(croots, e.Formatters), e.Separator)
-				w[structKey] = stringer.String()
-			}
-			if !e.DisableTypePrefix {
-				croots = append(croots, f.Type().Name())
+				e.fdumpLeaf(w, safeString(stringer), croots)
 			}
+			croots = e.typePrefix(croots, f.Type(), mapValuePosition)
 		}
 
 		if err := e.fdumpInterface(w, value.Interface(), croots); err != nil {
@@ -285,130 +1041,243 @@ func (e *Encoder) fDumpMap(w map[string]interface{}, i interface{}, roots []stri
 		}
 	}
 
-	if e.ExtraFields.Len {
-		nodeLen := append(roots, "__Len__")
-		nodeLenFormatted := strings.Join(sliceFormat(nodeLen, e.Formatters), e.Separator)
-		w[nodeLenFormatted] = lenKeys
+	if e.ExtraFields.Len || truncated {
+		e.setExtra(w, roots, "__Len__", lenKeys)
+	}
+	if detailed {
+		e.fdumpLeaf(w, w.endDetail(), roots)
 	}
-	if e.ExtraFields.DetailedMap {
-		if len(roots) != 0 {
-			structKey := strings.Join(sliceFormat(roots, e.Formatters), e.Separator)
-			w[structKey] = i
+	return nil
+}
+
+// fdumpSeqPairs dumps the pairs yielded by an iter.Seq2 as map entries, in yield order
+func (e *Encoder) fdumpSeqPairs(w *dumpState, pairs []seqPair, roots []segment) error {
+	w.container(roots, detailObject)
+	for _, p := range pairs {
+		key := e.mapKey(p.key)
+		if key == "" {
+			switch e.EmptyMapKeys {
+			case EmptyMapKeysPlaceholder:
+				key = EmptyMapKeyPlaceholder
+			case EmptyMapKeysError:
+				return e.newError(roots, p.key.Type(), ErrEmptyMapKey)
+			default:
+				continue
+			}
+		}
+		key, err := e.mapKeySegment(roots, key)
+		if err != nil {
+			return err
+		}
+		w.entries++
+		croots := append(roots[:len(roots):len(roots)], segment{name: key, kind: MapKeySegment, entry: w.entries})
+		if err := e.fdumpInterface(w, p.value.Interface(), croots); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func (e *Encoder) fdumpStruct(w map[string]interface{}, s reflect.Value, roots []string) error {
-	if e.ExtraFields.DetailedStruct {
-		if e.ExtraFields.Len {
-			nodeLen := append(roots, "__Len__")
-			nodeLenFormatted := strings.Join(sliceFormat(nodeLen, e.Formatters), e.Separator)
-			w[nodeLenFormatted] = s.NumField()
+func (e *Encoder) fdumpStruct(w *dumpState, s reflect.Value, roots []segment) error {
+	if e.ExtraFields.DetailedStruct && e.ExtraFields.Len {
+		e.setExtra(w, roots, "__Len__", s.NumField())
+	}
+	w.container(roots, detailObject)
+	detailed := e.ExtraFields.DetailedStruct && s.CanInterface() && len(roots) > 1
+	if detailed {
+		w.beginDetail(roots, detailObject)
+	}
+
+	atLeastOneField, err := e.fdumpFields(w, s, roots, e.dominantFields(s.Type()), nil)
+	if err != nil {
+		return err
+	}
+
+	var stringed bool
+	if !atLeastOneField {
+		stringer, ok := e.stringer(s.Interface())
+		if ok {
+			e.fdumpLeaf(w, safeString(stringer), roots)
+			stringed = true
 		}
+	}
 
-		structKey := strings.Join(sliceFormat(roots, e.Formatters), e.Separator)
-		if s.CanInterface() && len(roots) > 1 {
-			w[structKey] = s.Interface()
+	if detailed {
+		// A struct without fields dumped through its String keeps it rather than an empty object
+		if v := w.endDetail(); !stringed {
+			e.fdumpLeaf(w, v, roots)
 		}
 	}
+	return nil
+}
 
+// fdumpFields dumps the fields of a struct, and of its inlined structs. The fields are only
+// dumped if they are the dominant ones, if any.
+func (e *Encoder) fdumpFields(w *dumpState, s reflect.Value, roots []segment, dominants map[string]string, index []int) (bool, error) {
 	var atLeastOneField bool
-	for i := 0; i < s.NumField(); i++ {
-		k := reflect.ValueOf(i).Kind()
-		if k == reflect.Ptr && reflect.ValueOf(i).IsNil() {
-			if len(roots) == 0 {
-				continue
-			}
-			k := strings.Join(sliceFormat(roots, e.Formatters), e.Separator)
-			w[k] = ""
-			atLeastOneField = true
+	for _, p := range e.structPlan(s.Type()) {
+		fv := s.Field(p.index)
+		if !fv.CanInterface() {
 			continue
 		}
-
-		if !s.Field(i).CanInterface() {
+		if p.omit || p.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if e.FieldFilter != nil && !e.FieldFilter(s.Type(), p.field) {
 			continue
 		}
-		var croots []string
-		var keyNameComputed bool
-		if e.ExtraFields.UseJSONTag {
-			tagValues := strings.Split(s.Type().Field(i).Tag.Get("json"), ",")
-			if len(tagValues) > 0 && tagValues[0] != "omitempty" && tagValues[0] != "" {
-				croots = append(roots, tagValues[0])
-				keyNameComputed = true
+		findex := append(index[:len(index):len(index)], p.index)
+		if p.inline {
+			if f := valueFromInterface(fv.Interface()); f.Kind() == reflect.Struct {
+				atLeastOneField = true
+				if _, err := e.fdumpFields(w, f, roots, dominants, findex); err != nil {
+					return atLeastOneField, err
+				}
+				continue
+			}
+			if p.field.Anonymous && fv.Kind() == reflect.Ptr && fv.IsNil() {
+				continue
 			}
 		}
-		if !keyNameComputed {
-			croots = append(roots, s.Type().Field(i).Name)
+		if dominants != nil && dominants[p.name] != indexKey(findex) {
+			continue
 		}
+		field := p.field
+		croots := append(roots, segment{name: p.name, kind: FieldSegment, field: &field})
 		atLeastOneField = true
-		if err := e.fdumpInterface(w, s.Field(i).Interface(), croots); err != nil {
-			return err
+		if e.ExtraFields.InterfaceType && field.Type.Kind() == reflect.Interface && !fv.IsNil() {
+			e.setExtra(w, croots, "__InterfaceType__", fv.Elem().Type().String())
 		}
-	}
-
-	if !atLeastOneField {
-		stringer, ok := s.Interface().(fmt.Stringer)
-		if ok {
-			structKey := strings.Join(sliceFormat(roots, e.Formatters), e.Separator)
-			w[structKey] = stringer.String()
+		if p.tag.masked {
+			e.fdumpLeaf(w, e.maskValue(fv.Interface(), p.tag.mask), croots)
+			continue
+		}
+		if err := e.fdumpInterface(w, fv.Interface(), croots); err != nil {
+			return atLeastOneField, err
 		}
 	}
-
-	return nil
+	return atLeastOneField, nil
 }
 
 // ToStringMap formats the argument as a map[string]string. It formats exactly the same as Dump.
-func (e *Encoder) ToStringMap(i interface{}) (res map[string]string, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if _, ok := r.(runtime.Error); ok {
-				panic(r)
-			}
-			err = r.(error)
-			buf := make([]byte, 1<<16)
-			runtime.Stack(buf, true)
-		}
-	}()
-	ires := map[string]interface{}{}
-	if err = e.fdumpInterface(ires, i, nil); err != nil {
-		return
+func (e *Encoder) ToStringMap(i interface{}) (map[string]string, error) {
+	ires, err := e.ToMap(i)
+	if err != nil {
+		return nil, err
 	}
-	res = map[string]string{}
+	res := map[string]string{}
 	for k, v := range ires {
-		res[k] = printValue(v)
+		e.multiline(res, k, e.printValue(v))
 	}
-	return
+	return res, nil
 }
 
 // ToMap dumps argument as a map[string]interface{}
 func (e *Encoder) ToMap(i interface{}) (res map[string]interface{}, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if _, ok := r.(runtime.Error); ok {
-				panic(r)
-			}
-			err = r.(error)
-			buf := make([]byte, 1<<16)
-			runtime.Stack(buf, true)
-		}
-	}()
-	res = map[string]interface{}{}
-	if err = e.fdumpInterface(res, i, nil); err != nil {
-		return
+	if !e.DisableRecover {
+		defer recoverError(&err)
+	}
+	w := e.newDumpState()
+	if err := e.fdumpInterface(w, i, e.rootSegments(i)); err != nil {
+		return nil, err
+	}
+	if w.err != nil {
+		return nil, w.err
 	}
-	return
+	if e.PostProcess != nil {
+		return e.PostProcess(w.values), nil
+	}
+	return w.values, nil
 }
 
-func (e *Encoder) ViperKey(s string) string {
-	if e.Prefix != "" {
-		s = strings.Replace(s, e.Prefix+e.Separator, "", 1)
+// FdumpWithPrefix is like Fdump, but the keys are prefixed with prefix instead of the Prefix of
+// the encoder. The encoder is not modified, so the same encoder can dump several values under
+// different prefixes concurrently.
+func (e *Encoder) FdumpWithPrefix(prefix string, i interface{}) error {
+	c := *e
+	c.Prefix = prefix
+	return c.Fdump(i)
+}
+
+// SdumpWithPrefix is like Sdump, but the keys are prefixed with prefix instead of the Prefix of
+// the encoder
+func (e *Encoder) SdumpWithPrefix(prefix string, i interface{}) (string, error) {
+	c := *e
+	c.Prefix = prefix
+	return c.Sdump(i)
+}
+
+// With returns a clone of the encoder configured with the options, for a single dump such as
+// e.With(WithPrefix("db")).Fdump(v)
+func (e *Encoder) With(opts ...Option) *Encoder {
+	c := e.Clone()
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// MustSdump is like Sdump but panics on error. It is meant for tests and debug logging, not for
+// production paths.
+func (e *Encoder) MustSdump(i interface{}) string {
+	s, err := e.Sdump(i)
+	if err != nil {
+		panic(err)
 	}
-	s = strings.Replace(s, e.Separator, ".", -1)
-	s = strings.ToLower(s)
 	return s
 }
 
+// MustToMap is like ToMap but panics on error. It is meant for tests and debug logging, not for
+// production paths.
+func (e *Encoder) MustToMap(i interface{}) map[string]interface{} {
+	m, err := e.ToMap(i)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// MustToStringMap is like ToStringMap but panics on error. It is meant for tests and debug
+// logging, not for production paths.
+func (e *Encoder) MustToStringMap(i interface{}) map[string]string {
+	m, err := e.ToStringMap(i)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// printValue renders a value with the options of the encoder
+func (e *Encoder) printValue(i interface{}) string {
+	if s, ok := i.(string); ok && e.QuoteStrings {
+		return strconv.Quote(s)
+	}
+	if e.NonFinitePlaceholder != "" && isNonFinite(i) {
+		return e.NonFinitePlaceholder
+	}
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if isNonFinite(i) {
+			break
+		}
+		if e.FloatPrecision > 0 {
+			return strconv.FormatFloat(v.Float(), 'f', e.FloatPrecision, v.Type().Bits())
+		}
+		if e.CanonicalNumbers {
+			return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+		}
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if e.RedactPointers {
+			return PointerPlaceholder
+		}
+	}
+	if _, ok := i.(fmt.Stringer); ok && e.DisableStringer {
+		return printWithoutStringer(i)
+	}
+	return printValue(i)
+}
+
 func printValue(i interface{}) string {
 	s, is := i.(string)
 	if is {
@@ -420,9 +1289,12 @@ func printValue(i interface{}) string {
 	}
 	stringer, is := i.(fmt.Stringer)
 	if is {
-		return stringer.String()
+		return safeString(stringer)
 	}
-	btes, err := json.Marshal(i)
+	if isNonFinite(i) {
+		return formatNonFinite(reflect.ValueOf(i).Float())
+	}
+	btes, err := safeMarshalJSON(i)
 	if err == nil {
 		compactedBuffer := new(bytes.Buffer)
 		err := json.Compact(compactedBuffer, btes)
@@ -433,3 +1305,24 @@ func printValue(i interface{}) string {
 	}
 	return fmt.Sprintf("%v", i)
 }
+
+// isNonFinite returns true for the NaN and infinite float values
+func isNonFinite(i interface{}) bool {
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+		return false
+	}
+	f := v.Float()
+	return math.IsNaN(f) || math.IsInf(f, 0)
+}
+
+func formatNonFinite(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	default:
+		return "-Inf"
+	}
+}
@@ -0,0 +1,59 @@
+package dump
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// ToEnvMap dumps i to a map of environment variables: keys are in UPPER_SNAKE_CASE, separated by
+// underscores, and the characters not allowed in variable names are replaced with underscores, as
+// with EnvSanitizer. An error wrapping ErrKeyCollision is returned if distinct keys get the same
+// name. It is the counterpart of ViperKey for environment variables.
+func (e *Encoder) ToEnvMap(i interface{}) (map[string]string, error) {
+	m, err := e.envEncoder(e.Prefix).ToStringMap(i)
+	if err != nil {
+		return nil, err
+	}
+	return sanitizeKeys(m, "_", EnvSanitizer())
+}
+
+// LoadEnv sets the leaves of target, a non-nil pointer, from the environment variables named as
+// ToEnvMap names them, with the given prefix. The environment variables which don't match any
+// leaf are ignored. See Patch for the supported types.
+func (e *Encoder) LoadEnv(target interface{}, prefix string) error {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	err := e.envEncoder(prefix).patch(target, env, envKey)
+	if errors.Is(err, ErrUnknownKey) {
+		return nil
+	}
+	return err
+}
+
+// LoadEnv sets the leaves of target from the environment variables, such as PREFIX_DATABASE_HOST
+// for the Host field of the Database field. The type names are not part of the variable names.
+// See Encoder.LoadEnv.
+func LoadEnv(target interface{}, prefix string) error {
+	e := newEncoder(nil, nil)
+	e.DisableTypePrefix = true
+	return e.LoadEnv(target, prefix)
+}
+
+// envEncoder returns a clone of the encoder computing the keys of the environment variables
+func (e *Encoder) envEncoder(prefix string) *Encoder {
+	c := e.Clone()
+	c.Separator = "_"
+	c.Prefix = envKey(prefix)
+	c.Formatters = append(c.Formatters, WithUpperSnakeCaseFormatter())
+	return c
+}
+
+// envKey names the environment variable of the key s with EnvSanitizer
+func envKey(s string) string {
+	return EnvSanitizer()(s, "_")
+}
@@ -0,0 +1,72 @@
+package dump_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestToEnvMap(t *testing.T) {
+	type Config struct {
+		HTTPServer string
+		Database   struct {
+			MaxConns int
+		}
+		Labels map[string]string
+	}
+	var a Config
+	a.HTTPServer = "localhost:8080"
+	a.Database.MaxConns = 10
+	a.Labels = map[string]string{"app.kubernetes.io/name": "api"}
+
+	e := dump.NewDefaultEncoder()
+	e.DisableTypePrefix = true
+	e.Prefix = "my-app"
+	envs, err := e.ToEnvMap(a)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"MY_APP_HTTP_SERVER":                   "localhost:8080",
+		"MY_APP_DATABASE_MAX_CONNS":            "10",
+		"MY_APP_LABELS_APP_KUBERNETES_IO_NAME": "api",
+	}, envs)
+
+	envs, err = dump.NewDefaultEncoder().ToEnvMap([]int{1})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"_0": "1"}, envs)
+
+	_, err = dump.NewDefaultEncoder().ToEnvMap(map[string]string{"a.b": "1", "a_b": "2"})
+	assert.True(t, errors.Is(err, dump.ErrKeyCollision))
+}
+
+func TestLoadEnv(t *testing.T) {
+	type Config struct {
+		HTTPServer string
+		Database   struct {
+			Host     string
+			MaxConns int
+		}
+		Debug bool
+	}
+	var cfg Config
+	cfg.Database.Host = "localhost"
+
+	t.Setenv("MYAPP_HTTP_SERVER", "0.0.0.0:80")
+	t.Setenv("MYAPP_DATABASE_MAX_CONNS", "20")
+	t.Setenv("MYAPP_UNKNOWN", "1")
+	require.NoError(t, dump.LoadEnv(&cfg, "myapp"))
+	assert.Equal(t, "0.0.0.0:80", cfg.HTTPServer)
+	assert.Equal(t, "localhost", cfg.Database.Host)
+	assert.Equal(t, 20, cfg.Database.MaxConns)
+	assert.False(t, cfg.Debug)
+
+	t.Setenv("MYAPP_CONFIG_DEBUG", "true")
+	require.NoError(t, dump.NewDefaultEncoder().LoadEnv(&cfg, "myapp"))
+	assert.True(t, cfg.Debug)
+
+	t.Setenv("MYAPP_DEBUG", "maybe")
+	assert.Error(t, dump.LoadEnv(&cfg, "myapp"))
+}
@@ -0,0 +1,34 @@
+package dump
+
+// IgnoreKeys omits the leaves whose key matches one of the glob patterns, such as "*.UpdatedAt"
+// or "*.ID". It is the option form of Encoder.ExcludeKeys.
+func IgnoreKeys(patterns ...string) Option {
+	return func(e *Encoder) {
+		e.ExcludeKeys(patterns...)
+	}
+}
+
+// Equal dumps a and b with the options and returns true if both canonical dumps are the same. It
+// is a lighter-weight alternative to reflect.DeepEqual for test assertions, as unexported fields
+// are not compared and keys can be ignored with the IgnoreKeys option. Options are applied after
+// WithCanonicalOutput.
+func Equal(a, b interface{}, opts ...Option) (bool, error) {
+	e := NewDefaultEncoder(append([]Option{WithCanonicalOutput()}, opts...)...)
+	ma, err := e.ToStringMap(a)
+	if err != nil {
+		return false, err
+	}
+	mb, err := e.ToStringMap(b)
+	if err != nil {
+		return false, err
+	}
+	if len(ma) != len(mb) {
+		return false, nil
+	}
+	for k, v := range ma {
+		if w, ok := mb[k]; !ok || v != w {
+			return false, nil
+		}
+	}
+	return true, nil
+}
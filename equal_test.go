@@ -0,0 +1,49 @@
+package dump_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestEqual(t *testing.T) {
+	type User struct {
+		ID        int
+		Name      string
+		Tags      []string
+		UpdatedAt time.Time
+	}
+	a := User{ID: 1, Name: "foo", Tags: []string{"a", "b"}, UpdatedAt: time.Now()}
+	b := User{ID: 2, Name: "foo", Tags: []string{"a", "b"}, UpdatedAt: time.Now().Add(time.Hour)}
+
+	eq, err := dump.Equal(a, a)
+	require.NoError(t, err)
+	assert.True(t, eq)
+
+	eq, err = dump.Equal(a, b)
+	require.NoError(t, err)
+	assert.False(t, eq)
+
+	eq, err = dump.Equal(a, b, dump.IgnoreKeys("*.ID", "*.UpdatedAt"))
+	require.NoError(t, err)
+	assert.True(t, eq)
+
+	b.Tags = append(b.Tags, "c")
+	eq, err = dump.Equal(a, b, dump.IgnoreKeys("*.ID", "*.UpdatedAt"))
+	require.NoError(t, err)
+	assert.False(t, eq)
+}
+
+func TestEqualMonotonicTime(t *testing.T) {
+	type U struct {
+		T time.Time
+	}
+	now := time.Now()
+	eq, err := dump.Equal(U{now}, U{now.Round(0)})
+	require.NoError(t, err)
+	assert.True(t, eq)
+}
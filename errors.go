@@ -0,0 +1,101 @@
+package dump
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// ErrUnsupportedType is returned in Strict mode for the values which can't be dumped, such as
+	// funcs, chans or unsafe pointers
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrCycleDetected is returned when a value references itself
+	ErrCycleDetected = errors.New("cycle detected")
+	// ErrEmptyMapKey is returned with EmptyMapKeysError for the map entries whose key is empty
+	ErrEmptyMapKey = errors.New("empty map key")
+	// ErrMaxDepth is returned in Strict mode when a value is deeper than Limits.MaxDepth
+	ErrMaxDepth = errors.New("max depth exceeded")
+	// ErrSeparatorInKey is returned with MapKeySeparatorError for the map keys containing the
+	// Separator
+	ErrSeparatorInKey = errors.New("separator in map key")
+	// ErrSkipLine is returned by a LineFunc to skip a line
+	ErrSkipLine = errors.New("skip line")
+	// ErrKeyCollision is returned when distinct keys are sanitized to the same key, or when leaves
+	// are dumped under the same key with Collisions
+	ErrKeyCollision = errors.New("key collision")
+	// ErrInvalidIndex is returned by Unflatten for the array indexes which can't be parsed or are
+	// out of range
+	ErrInvalidIndex = errors.New("invalid array index")
+)
+
+// Error is an error raised while dumping a value, it wraps one of the sentinel errors, such as
+// ErrCycleDetected, and can be checked with errors.Is
+type Error struct {
+	// Key is the key of the value which can't be dumped
+	Key string
+	// Type is the type of the value which can't be dumped
+	Type reflect.Type
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("dump %v: %v", e.Type, e.Err)
+	}
+	return fmt.Sprintf("dump %s (%v): %v", e.Key, e.Type, e.Err)
+}
+
+// Unwrap returns the wrapped sentinel error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Encoder) newError(roots []segment, t reflect.Type, err error) error {
+	return &Error{Key: e.formatKey(roots), Type: t, Err: err}
+}
+
+// PanicError is the error returned when a panic is recovered while dumping a value, for example
+// raised by a Stringer or a DumperFunc
+type PanicError struct {
+	// Value is the recovered value
+	Value interface{}
+	// Stack is the stack trace of the goroutine which panicked
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the recovered value if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverError turns the panics raised while dumping a value into a PanicError. Runtime errors are
+// not recovered.
+func recoverError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if _, ok := r.(runtime.Error); ok {
+		panic(r)
+	}
+	*err = &PanicError{Value: r, Stack: debug.Stack()}
+}
+
+// isReference returns true for the values which can reference themselves
+func isReference(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map:
+		return !v.IsNil()
+	case reflect.Slice:
+		return v.Len() > 0
+	}
+	return false
+}
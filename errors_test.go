@@ -0,0 +1,108 @@
+package dump_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+type panicking struct {
+	Name string
+}
+
+func panickingEncoder() *dump.Encoder {
+	e := dump.NewDefaultEncoder()
+	e.RegisterDumper(reflect.TypeOf(panicking{}), func(v reflect.Value) interface{} {
+		panic("boom")
+	})
+	return e
+}
+
+func TestErrCycleDetected(t *testing.T) {
+	type Node struct {
+		Name string
+		Next *Node
+	}
+	a := &Node{Name: "a"}
+	b := &Node{Name: "b", Next: a}
+	a.Next = b
+
+	_, err := dump.ToStringMap(a)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, dump.ErrCycleDetected))
+	var derr *dump.Error
+	require.True(t, errors.As(err, &derr))
+	assert.Equal(t, "Node.Next.Next", derr.Key)
+
+	m := map[string]interface{}{"name": "m"}
+	m["self"] = m
+	_, err = dump.ToMap(m)
+	assert.True(t, errors.Is(err, dump.ErrCycleDetected))
+
+	// The same pointer referenced twice is not a cycle
+	type Pair struct {
+		Left, Right *Node
+	}
+	leaf := &Node{Name: "leaf"}
+	res, err := dump.ToStringMap(Pair{Left: leaf, Right: leaf})
+	require.NoError(t, err)
+	assert.Equal(t, "leaf", res["Pair.Left.Name"])
+	assert.Equal(t, "leaf", res["Pair.Right.Name"])
+}
+
+func TestStrictErrors(t *testing.T) {
+	type T struct {
+		A struct {
+			B struct {
+				C string
+			}
+		}
+		Callback func()
+	}
+	var a T
+	a.A.B.C = "deep"
+
+	e := dump.NewDefaultEncoder()
+	e.Limits.MaxDepth = 2
+	m, err := e.ToStringMap(a)
+	require.NoError(t, err)
+	assert.NotContains(t, m, "T.A.B.C")
+
+	e.Strict = true
+	_, err = e.ToStringMap(a)
+	assert.True(t, errors.Is(err, dump.ErrMaxDepth))
+
+	e.Limits.MaxDepth = 0
+	_, err = e.ToStringMap(T{Callback: func() {}})
+	assert.True(t, errors.Is(err, dump.ErrUnsupportedType))
+}
+
+func TestRecoveredPanic(t *testing.T) {
+	type T struct {
+		P panicking
+	}
+	_, err := panickingEncoder().ToStringMap(T{})
+	assert.EqualError(t, err, "boom")
+}
+
+func TestDisableRecover(t *testing.T) {
+	type T struct {
+		P panicking
+	}
+	_, err := panickingEncoder().ToStringMap(T{})
+	var perr *dump.PanicError
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, "boom", perr.Value)
+	assert.Contains(t, string(perr.Stack), "panickingEncoder")
+
+	e := panickingEncoder()
+	e.DisableRecover = true
+	assert.PanicsWithValue(t, "boom", func() {
+		_, _ = e.ToStringMap(T{})
+	})
+}
@@ -0,0 +1,39 @@
+package dump
+
+import "regexp"
+
+// ExcludeKeys omits the leaves whose key matches one of the glob patterns, such as "Server.TLS.*"
+// to drop a whole subtree. It is the method form of the IgnoreKeys option. As with RedactKeys,
+// patterns are matched case-insensitively against the formatted keys.
+func (e *Encoder) ExcludeKeys(patterns ...string) {
+	for _, p := range patterns {
+		e.ignored = append(e.ignored, globToRegexp(p, true))
+	}
+}
+
+// IncludeKeys only dumps the leaves whose key matches one of the glob patterns, such as
+// "*.ID" or "Server.*". Patterns are matched case-insensitively against the formatted keys, the
+// excluded keys are omitted even if they match.
+func (e *Encoder) IncludeKeys(patterns ...string) {
+	for _, p := range patterns {
+		e.included = append(e.included, globToRegexp(p, true))
+	}
+}
+
+// ExcludeKeysRegexp omits the leaves whose key matches one of the regular expressions. As with
+// ExcludeKeys, a denied key is omitted even if it is allowed.
+func (e *Encoder) ExcludeKeysRegexp(res ...*regexp.Regexp) {
+	e.ignored = append(e.ignored, res...)
+}
+
+// IncludeKeysRegexp only dumps the leaves whose key matches one of the regular expressions, or one
+// of the IncludeKeys patterns
+func (e *Encoder) IncludeKeysRegexp(res ...*regexp.Regexp) {
+	e.included = append(e.included, res...)
+}
+
+// filtered tells if the leaf of key k is omitted by the exclusion or inclusion patterns, the
+// exclusions win
+func (e *Encoder) filtered(k string) bool {
+	return matchAny(e.ignored, k) || len(e.included) > 0 && !matchAny(e.included, k)
+}
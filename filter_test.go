@@ -0,0 +1,102 @@
+package dump_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+type filterServer struct {
+	Host string
+	Port int
+	TLS  struct {
+		Cert string
+		Key  string
+	}
+}
+
+func newFilterServer() filterServer {
+	var s filterServer
+	s.Host = "localhost"
+	s.Port = 443
+	s.TLS.Cert = "cert.pem"
+	s.TLS.Key = "key.pem"
+	return s
+}
+
+func TestExcludeKeys(t *testing.T) {
+	e := dump.NewDefaultEncoder()
+	e.DisableTypePrefix = true
+	e.ExcludeKeys("TLS.*")
+	m, err := e.ToStringMap(newFilterServer())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Host": "localhost", "Port": "443"}, m)
+}
+
+func TestIncludeKeys(t *testing.T) {
+	e := dump.NewDefaultEncoder()
+	e.DisableTypePrefix = true
+	e.IncludeKeys("Host", "TLS.*")
+	e.ExcludeKeys("*.Key")
+	m, err := e.ToStringMap(newFilterServer())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Host": "localhost", "TLS.Cert": "cert.pem"}, m)
+}
+
+func TestKeysRegexp(t *testing.T) {
+	e := dump.NewDefaultEncoder()
+	e.DisableTypePrefix = true
+	e.IncludeKeysRegexp(regexp.MustCompile(`^(Host|Port)$`), regexp.MustCompile(`^TLS\.`))
+	e.ExcludeKeysRegexp(regexp.MustCompile(`(?i)key|port`))
+	m, err := e.ToStringMap(newFilterServer())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Host": "localhost", "TLS.Cert": "cert.pem"}, m)
+}
+
+func TestKeysCaseInsensitive(t *testing.T) {
+	e := dump.NewDefaultEncoder()
+	e.DisableTypePrefix = true
+	e.IncludeKeys("host", "tls.*")
+	e.ExcludeKeys("*.KEY")
+	m, err := e.ToStringMap(newFilterServer())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Host": "localhost", "TLS.Cert": "cert.pem"}, m)
+
+	eq, err := dump.Equal(newFilterServer(), filterServer{Host: "localhost"}, dump.IgnoreKeys("filterserver.port", "*.tls.*"))
+	require.NoError(t, err)
+	assert.True(t, eq)
+}
+
+func TestKeysExtraFields(t *testing.T) {
+	type Server struct {
+		Host string
+		TLS  struct {
+			Cert string
+			Keys []string
+		}
+	}
+	var s Server
+	s.Host = "localhost"
+	s.TLS.Cert = "cert.pem"
+	s.TLS.Keys = []string{"a", "b"}
+
+	e := dump.NewDefaultEncoder()
+	e.ExtraFields.Type = true
+	e.ExtraFields.Len = true
+	e.ExcludeKeys("Server.TLS.*")
+	m, err := e.ToStringMap(s)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Server.Host": "localhost", "__Type__": "Server"}, m)
+
+	e = dump.NewDefaultEncoder()
+	e.ExtraFields.Type = true
+	e.ExtraFields.Len = true
+	e.IncludeKeys("Server.Host")
+	m, err = e.ToStringMap(s)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Server.Host": "localhost"}, m)
+}
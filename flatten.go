@@ -0,0 +1,132 @@
+package dump
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var stringType = reflect.TypeOf("")
+
+// MapKeySeparatorMode defines how the map keys containing the Separator are handled, as they
+// can't be told apart from nested keys
+type MapKeySeparatorMode int
+
+const (
+	// MapKeySeparatorKeep keeps the separator in the map keys
+	MapKeySeparatorKeep MapKeySeparatorMode = iota
+	// MapKeySeparatorReplace replaces the separator in the map keys with MapKeySeparatorReplacement
+	MapKeySeparatorReplace
+	// MapKeySeparatorError returns an error wrapping ErrSeparatorInKey
+	MapKeySeparatorError
+)
+
+// MapKeySeparatorReplacement replaces the Separator in the map keys with MapKeySeparatorReplace
+const MapKeySeparatorReplacement = "_"
+
+// mapKeySegment applies the MapKeySeparator mode to the map key k
+func (e *Encoder) mapKeySegment(roots []segment, k string) (string, error) {
+	if e.Separator == "" || e.MapKeySeparator == MapKeySeparatorKeep || !strings.Contains(k, e.Separator) {
+		return k, nil
+	}
+	if e.MapKeySeparator == MapKeySeparatorError {
+		return "", &Error{Key: e.formatKey(append(roots, segment{name: k, kind: MapKeySegment})), Type: stringType, Err: ErrSeparatorInKey}
+	}
+	return strings.Replace(k, e.Separator, MapKeySeparatorReplacement, -1), nil
+}
+
+// FlattenMap flattens m, such as a decoded JSON or YAML document, with an encoder configured by the
+// options. See Encoder.FlattenMap.
+func FlattenMap(m map[string]interface{}, opts ...Option) (map[string]interface{}, error) {
+	return NewDefaultEncoder(opts...).FlattenMap(m)
+}
+
+// FlattenMap flattens m, such as a decoded JSON or YAML document made of maps, slices and scalars,
+// without the reflection of ToMap for the map[string]interface{}, map[interface{}]interface{} and
+// []interface{} values, the other values are dumped as by ToMap. The keys are built as by ToMap, the map keys containing the
+// Separator are handled according to MapKeySeparator, and the distinct paths flattened to the same
+// key, such as {"a.b": 1, "a": {"b": 2}}, are detected with Collisions. The map entries are walked
+// in key order, so the last one wins if the collisions are ignored.
+func (e *Encoder) FlattenMap(m map[string]interface{}) (res map[string]interface{}, err error) {
+	if !e.DisableRecover {
+		defer recoverError(&err)
+	}
+	w := e.newDumpState()
+	if err := e.flattenValue(w, m, e.rootSegments(m)); err != nil {
+		return nil, err
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	if e.PostProcess != nil {
+		return e.PostProcess(w.values), nil
+	}
+	return w.values, nil
+}
+
+func (e *Encoder) flattenValue(w *dumpState, v interface{}, roots []segment) error {
+	if w.stopped {
+		return w.err
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return naturalLess(keys[i], keys[j]) })
+		for _, k := range keys {
+			if err := e.flattenEntry(w, k, v[k], roots); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		type entry struct {
+			key   string
+			value interface{}
+		}
+		entries := make([]entry, 0, len(v))
+		for k, kv := range v {
+			entries = append(entries, entry{key: fmt.Sprint(k), value: kv})
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return naturalLess(entries[i].key, entries[j].key) })
+		for _, en := range entries {
+			if err := e.flattenEntry(w, en.key, en.value, roots); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, elem := range v {
+			if err := e.flattenValue(w, elem, e.indexRoots(roots, i)); err != nil {
+				return err
+			}
+		}
+	case nil:
+		if len(roots) > 0 {
+			e.fdumpLeaf(w, "", roots)
+		}
+	default:
+		return e.fdumpInterface(w, v, roots)
+	}
+	return nil
+}
+
+func (e *Encoder) flattenEntry(w *dumpState, k string, v interface{}, roots []segment) error {
+	if k == "" {
+		switch e.EmptyMapKeys {
+		case EmptyMapKeysPlaceholder:
+			k = EmptyMapKeyPlaceholder
+		case EmptyMapKeysError:
+			return e.newError(roots, stringType, ErrEmptyMapKey)
+		default:
+			return nil
+		}
+	}
+	k, err := e.mapKeySegment(roots, k)
+	if err != nil {
+		return err
+	}
+	w.entries++
+	return e.flattenValue(w, v, append(roots[:len(roots):len(roots)], segment{name: k, kind: MapKeySegment, entry: w.entries}))
+}
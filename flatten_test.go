@@ -0,0 +1,78 @@
+package dump_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestFlattenMap(t *testing.T) {
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"name": "api",
+		"replicas": 3,
+		"ports": [80, 443],
+		"labels": {"app.kubernetes.io/name": "api"},
+		"empty": null
+	}`), &doc))
+
+	m, err := dump.FlattenMap(doc)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":                          "api",
+		"replicas":                      float64(3),
+		"ports.ports0":                  float64(80),
+		"ports.ports1":                  float64(443),
+		"labels.app.kubernetes.io_name": "api",
+		"empty":                         "",
+	}, m)
+
+	m, err = dump.FlattenMap(doc, func(e *dump.Encoder) {
+		e.MapKeySeparator = dump.MapKeySeparatorReplace
+		e.ArrayJSONNotation = true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "api", m["labels.app_kubernetes_io_name"])
+	assert.Equal(t, float64(443), m["ports[1]"])
+
+	_, err = dump.FlattenMap(doc, func(e *dump.Encoder) {
+		e.MapKeySeparator = dump.MapKeySeparatorError
+	})
+	assert.True(t, errors.Is(err, dump.ErrSeparatorInKey))
+}
+
+func TestFlattenMapConflicts(t *testing.T) {
+	doc := map[string]interface{}{
+		"a.b": 1,
+		"a":   map[string]interface{}{"b": 2},
+	}
+
+	m, err := dump.FlattenMap(doc)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a.b": 1}, m)
+
+	_, err = dump.FlattenMap(doc, func(e *dump.Encoder) {
+		e.Collisions = dump.CollisionsExact
+	})
+	assert.True(t, errors.Is(err, dump.ErrKeyCollision))
+}
+
+func TestFlattenMapTypedValues(t *testing.T) {
+	m, err := dump.FlattenMap(map[string]interface{}{
+		"tags": []string{"a", "b"},
+		"m":    map[string]string{"k": "v"},
+		"yaml": map[interface{}]interface{}{"port": 80},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tags.tags0": "a",
+		"tags.tags1": "b",
+		"m.k":        "v",
+		"yaml.port":  80,
+	}, m)
+}
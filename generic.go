@@ -0,0 +1,36 @@
+package dump
+
+// Flatten dumps v as a map[string]string with an encoder configured by the options, it formats
+// exactly the same as ToStringMap.
+func Flatten[T any](v T, opts ...Option) (map[string]string, error) {
+	return NewDefaultEncoder(opts...).ToStringMap(v)
+}
+
+// TypedEncoder dumps values of type T. It is created once per type and caches the fields of the
+// dumped structs and the formatted keys, so that repeated dumps in hot paths are faster. Its
+// configuration can't be changed once created, and it is safe for concurrent use.
+type TypedEncoder[T any] struct {
+	e *Encoder
+}
+
+// NewTypedEncoder returns a TypedEncoder configured by the options
+func NewTypedEncoder[T any](opts ...Option) *TypedEncoder[T] {
+	e := NewDefaultEncoder(opts...)
+	e.cache = &encoderCache{}
+	return &TypedEncoder[T]{e: e}
+}
+
+// ToMap dumps v as a map[string]interface{}
+func (t *TypedEncoder[T]) ToMap(v T) (map[string]interface{}, error) {
+	return t.e.ToMap(v)
+}
+
+// ToStringMap formats v as a map[string]string
+func (t *TypedEncoder[T]) ToStringMap(v T) (map[string]string, error) {
+	return t.e.ToStringMap(v)
+}
+
+// Sdump returns a string with v formatted exactly the same as Dump
+func (t *TypedEncoder[T]) Sdump(v T) (string, error) {
+	return t.e.Sdump(v)
+}
@@ -0,0 +1,60 @@
+package dump_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestFlatten(t *testing.T) {
+	type Server struct {
+		Host string
+		Port int
+	}
+	m, err := dump.Flatten(Server{Host: "localhost", Port: 8080}, dump.WithSecurityDefaults())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Server.Host": "localhost", "Server.Port": "8080"}, m)
+
+	m, err = dump.Flatten([]string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"0": "a", "1": "b"}, m)
+}
+
+type typedServer struct {
+	Host    string `json:"host"`
+	Port    int    `json:"port,omitempty"`
+	Debug   bool   `json:"-"`
+	Options struct {
+		Timeout int
+		Retries int
+	}
+	Tags []string
+}
+
+func TestTypedEncoder(t *testing.T) {
+	s := typedServer{Host: "localhost", Port: 8080, Tags: []string{"a", "b"}}
+	s.Options.Timeout = 30
+
+	opts := []dump.Option{func(e *dump.Encoder) {
+		e.ExtraFields.UseJSONTag = true
+		e.Formatters = append(e.Formatters, dump.WithDefaultUpperCaseFormatter())
+	}}
+	typed := dump.NewTypedEncoder[typedServer](opts...)
+	want, err := dump.NewDefaultEncoder(opts...).ToStringMap(s)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		got, err := typed.ToStringMap(s)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	assert.Equal(t, "localhost", want["TYPEDSERVER.HOST"])
+	assert.NotContains(t, want, "TYPEDSERVER.DEBUG")
+
+	s.Port = 0
+	got, err := typed.ToStringMap(s)
+	require.NoError(t, err)
+	assert.NotContains(t, got, "TYPEDSERVER.PORT")
+}
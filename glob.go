@@ -0,0 +1,28 @@
+package dump
+
+import (
+	"regexp"
+	"strings"
+)
+
+// globToRegexp compiles a glob pattern, where * matches any sequence of characters
+// and ? matches any single character, to an anchored regular expression.
+func globToRegexp(pattern string, ignoreCase bool) *regexp.Regexp {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	expr = strings.Replace(expr, `\?`, ".", -1)
+	expr = "^" + expr + "$"
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.MustCompile(expr)
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
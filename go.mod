@@ -3,6 +3,7 @@ module github.com/fsamin/go-dump
 require (
 	github.com/spf13/viper v1.7.1 // tests
 	github.com/stretchr/testify v1.6.1 // tests
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -22,7 +23,6 @@ require (
 	golang.org/x/text v0.3.2 // indirect
 	gopkg.in/ini.v1 v1.51.0 // indirect
 	gopkg.in/yaml.v2 v2.2.4 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
 
-go 1.18
+go 1.23
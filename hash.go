@@ -0,0 +1,31 @@
+package dump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// Hash returns the hex encoded SHA-256 of the dump of i. The keys are sorted, so two values
+// dumped the same way have the same hash, which cheaply detects whether a value changed.
+func (e *Encoder) Hash(i interface{}) (string, error) {
+	m, err := e.ToStringMap(i)
+	if err != nil {
+		return "", err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		// Keys and values are NUL terminated, so that no value can be mistaken for another key
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(m[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
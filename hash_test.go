@@ -0,0 +1,33 @@
+package dump_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestEncoderHash(t *testing.T) {
+	type Config struct {
+		Host  string
+		Port  int
+		Peers map[string]string
+	}
+	a := Config{Host: "localhost", Port: 8080, Peers: map[string]string{"a": "10.0.0.1", "b": "10.0.0.2"}}
+	b := Config{Host: "localhost", Port: 8080, Peers: map[string]string{"b": "10.0.0.2", "a": "10.0.0.1"}}
+
+	e := dump.NewDefaultEncoder()
+	ha, err := e.Hash(a)
+	require.NoError(t, err)
+	hb, err := e.Hash(b)
+	require.NoError(t, err)
+	assert.Len(t, ha, 64)
+	assert.Equal(t, ha, hb)
+
+	b.Port = 8081
+	hb, err = e.Hash(b)
+	require.NoError(t, err)
+	assert.NotEqual(t, ha, hb)
+}
@@ -1,13 +1,119 @@
 package dump
 
 import (
+	"math"
 	"reflect"
 	"strings"
+	"unicode"
 )
 
 // KeyFormatterFunc is a type for key formatting
 type KeyFormatterFunc func(s string, level int) string
 
+// SegmentKind is the kind of a key segment
+type SegmentKind int
+
+const (
+	// FieldSegment is the name of a struct field
+	FieldSegment SegmentKind = iota
+	// MapKeySegment is a map key
+	MapKeySegment
+	// IndexSegment is an array or slice element
+	IndexSegment
+	// TypeSegment is the name of a struct type
+	TypeSegment
+	// ExtraSegment is an extra field, such as __Type__ or __Len__
+	ExtraSegment
+	// RootSegment is the RootName of the dumped values which are not structs
+	RootSegment
+)
+
+// segment is an element of the path of a dumped value
+type segment struct {
+	name  string
+	kind  SegmentKind
+	field *reflect.StructField
+	// index is the index of the IndexSegment elements
+	index int
+	// entry numbers the MapKeySegment entries of a dump, so that the keys rendered the same way,
+	// such as 1 and "1", are told apart
+	entry int
+	// parent is the segment of the array replaced by the IndexSegment elements in JSON notation or
+	// named with the IndexFormatter, such as "List" for "List[0]"
+	parent *segment
+}
+
+// depth returns the depth of a path, type and root names don't count
+func depth(roots []segment) int {
+	var d int
+	for _, s := range roots {
+		if s.kind != TypeSegment && s.kind != RootSegment {
+			d++
+		}
+	}
+	return d
+}
+
+// truncate cuts s to n runes, and appends an ellipsis if it was longer
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+func segmentNames(roots []segment) []string {
+	names := make([]string, len(roots))
+	for i, s := range roots {
+		names[i] = s.name
+	}
+	return names
+}
+
+// KeyContext describes the key segment being formatted by a KeyFormatterFuncV2
+type KeyContext struct {
+	// Segment is the segment to format, with the Formatters of the encoder already applied
+	Segment string
+	// Path is the full path of unformatted segments
+	Path []string
+	// Level is the position of the segment in the path
+	Level int
+	// Kind tells if the segment is a field name, a map key, an array index...
+	Kind SegmentKind
+	// Field is the struct field of FieldSegment segments, nil otherwise
+	Field *reflect.StructField
+}
+
+// KeyFormatterFuncV2 is a type for key formatting aware of the context of the segment
+type KeyFormatterFuncV2 func(ctx KeyContext) string
+
+// ValueFormatterFunc is a type for value formatting, it receives the key and the value of each leaf
+// and returns the value to dump
+type ValueFormatterFunc func(key string, v reflect.Value) reflect.Value
+
+// KindHookFunc normalizes a leaf, it receives and returns its value
+type KindHookFunc func(v reflect.Value) reflect.Value
+
+// RoundFloats is a KindHookFunc rounding the float values to the number of decimals
+func RoundFloats(decimals int) KindHookFunc {
+	p := math.Pow10(decimals)
+	return func(v reflect.Value) reflect.Value {
+		r := reflect.New(v.Type()).Elem()
+		r.SetFloat(math.Round(v.Float()*p) / p)
+		return r
+	}
+}
+
+// TrimStrings is a KindHookFunc removing the leading and trailing spaces of the string values
+func TrimStrings() KindHookFunc {
+	return func(v reflect.Value) reflect.Value {
+		r := reflect.New(v.Type()).Elem()
+		r.SetString(strings.TrimSpace(v.String()))
+		return r
+	}
+}
+
 // WithLowerCaseFormatter formats keys in lowercase
 func WithLowerCaseFormatter() KeyFormatterFunc {
 	return func(s string, level int) string {
@@ -41,6 +147,57 @@ func WithDefaultFormatter() KeyFormatterFunc {
 	}
 }
 
+// WithSnakeCaseFormatter formats keys in snake_case and apply default formatting.
+// CamelCase identifiers are split on case changes, so "HTTPServerURL" becomes "http_server_url".
+func WithSnakeCaseFormatter() KeyFormatterFunc {
+	f := WithDefaultFormatter()
+	return func(s string, level int) string {
+		return convertCase(f(s, level), func(words []string) string {
+			return strings.ToLower(strings.Join(words, "_"))
+		})
+	}
+}
+
+// WithUpperSnakeCaseFormatter formats keys in SCREAMING_SNAKE_CASE and apply default formatting
+func WithUpperSnakeCaseFormatter() KeyFormatterFunc {
+	f := WithDefaultFormatter()
+	return func(s string, level int) string {
+		return convertCase(f(s, level), func(words []string) string {
+			return strings.ToUpper(strings.Join(words, "_"))
+		})
+	}
+}
+
+// WithCamelCaseFormatter formats keys in camelCase and apply default formatting,
+// so "HTTPServerURL" becomes "httpServerUrl"
+func WithCamelCaseFormatter() KeyFormatterFunc {
+	f := WithDefaultFormatter()
+	return func(s string, level int) string {
+		return convertCase(f(s, level), func(words []string) string {
+			for i, w := range words {
+				w = strings.ToLower(w)
+				if i > 0 {
+					r := []rune(w)
+					r[0] = unicode.ToUpper(r[0])
+					w = string(r)
+				}
+				words[i] = w
+			}
+			return strings.Join(words, "")
+		})
+	}
+}
+
+// WithKebabCaseFormatter formats keys in kebab-case and apply default formatting
+func WithKebabCaseFormatter() KeyFormatterFunc {
+	f := WithDefaultFormatter()
+	return func(s string, level int) string {
+		return convertCase(f(s, level), func(words []string) string {
+			return strings.ToLower(strings.Join(words, "-"))
+		})
+	}
+}
+
 // NoFormatter doesn't do anything, so to be sure to avoid keys formatting, use only this formatter
 func NoFormatter() KeyFormatterFunc {
 	return func(s string, level int) string {
@@ -76,11 +233,26 @@ func validAndNotEmpty(v reflect.Value) bool {
 	return false
 }
 
-func sliceFormat(s []string, formatters []KeyFormatterFunc) []string {
-	for i := range s {
-		s[i] = format(s[i], formatters, i)
+// formatKey applies the formatters on each segment of the path and joins them with the separator
+func (e *Encoder) formatKey(roots []segment) string {
+	var path []string
+	if len(e.ContextFormatters) > 0 {
+		path = segmentNames(roots)
 	}
-	return s
+	formatted := make([]string, len(roots))
+	for i, s := range roots {
+		formatted[i] = format(s.name, e.Formatters, i)
+		for _, f := range e.ContextFormatters {
+			formatted[i] = f(KeyContext{
+				Segment: formatted[i],
+				Path:    path,
+				Level:   i,
+				Kind:    s.kind,
+				Field:   s.field,
+			})
+		}
+	}
+	return strings.Join(formatted, e.Separator)
 }
 
 func format(s string, formatters []KeyFormatterFunc, level int) string {
@@ -89,3 +261,110 @@ func format(s string, formatters []KeyFormatterFunc, level int) string {
 	}
 	return s
 }
+
+// convertCase splits s in words and joins them with the join function. Leading and trailing
+// underscores, such as in the __Type__ and __Len__ keys, are kept as is.
+func convertCase(s string, join func(words []string) string) string {
+	trimmed := strings.Trim(s, "_")
+	if trimmed == "" {
+		return s
+	}
+	start := strings.Index(s, trimmed)
+	return s[:start] + join(splitWords(trimmed)) + s[start+len(trimmed):]
+}
+
+// splitWords splits a string on underscores, dashes, spaces and case changes. Acronyms are kept
+// together, so "HTTPServerURL" is split in "HTTP", "Server" and "URL".
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == ' ' {
+			flush()
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// typePrefix appends the type segment of the struct type t, at the given position, to roots if the
+// TypePrefix mode requires it. Anonymous structs are named AnonymousTypeName, they have no type
+// segment if it is empty.
+func (e *Encoder) typePrefix(roots []segment, t reflect.Type, pos typePosition) []segment {
+	if !e.typePrefixed(pos) {
+		return roots
+	}
+	name := t.Name()
+	if name == "" {
+		name = e.AnonymousTypeName
+	}
+	if name == "" {
+		return roots
+	}
+	return append(roots, segment{name: name, kind: TypeSegment})
+}
+
+// rootSegments returns the path of the dumped value i: the RootName if i is not a struct
+func (e *Encoder) rootSegments(i interface{}) []segment {
+	if e.RootName == "" {
+		return nil
+	}
+	v := reflect.ValueOf(i)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() == reflect.Struct {
+		return nil
+	}
+	return []segment{{name: e.RootName, kind: RootSegment}}
+}
+
+// structPrefix appends the type segment of the struct type t dumped under roots, unless roots
+// already ends with one, such as for the map values
+func (e *Encoder) structPrefix(roots []segment, t reflect.Type) []segment {
+	if len(roots) == 0 {
+		return e.typePrefix(roots, t, rootPosition)
+	}
+	if roots[len(roots)-1].kind == TypeSegment {
+		return roots
+	}
+	return e.typePrefix(roots, t, nestedPosition)
+}
+
+func (e *Encoder) typePrefixed(pos typePosition) bool {
+	mode := e.TypePrefix
+	if e.DisableTypePrefix {
+		mode = TypePrefixNever
+	}
+	switch mode {
+	case TypePrefixNever:
+		return false
+	case TypePrefixRootOnly:
+		return pos == rootPosition
+	case TypePrefixAlways:
+		return true
+	case TypePrefixMapValuesOnly:
+		return pos == mapValuePosition
+	default:
+		return pos != nestedPosition
+	}
+}
@@ -0,0 +1,128 @@
+package dump
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodySize is the default maximum number of bytes of the HTTP bodies captured by DumpHTTPRequest and DumpHTTPResponse
+const DefaultMaxBodySize = 64 * 1024
+
+// SensitiveHeaders are the HTTP headers redacted by DumpHTTPRequest and DumpHTTPResponse
+var SensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// HTTPRequest is the snapshot of an http.Request dumped by DumpHTTPRequest
+type HTTPRequest struct {
+	Method        string
+	URL           string
+	Proto         string
+	Host          string
+	Header        map[string]string
+	ContentLength int64
+	Body          string
+}
+
+// HTTPResponse is the snapshot of an http.Response dumped by DumpHTTPResponse
+type HTTPResponse struct {
+	Status        string
+	StatusCode    int
+	Proto         string
+	Header        map[string]string
+	ContentLength int64
+	Body          string
+}
+
+// DumpHTTPRequest dumps an http.Request as a map[string]string. Headers are flattened, sensitive
+// headers are redacted and the body is captured up to Limits.MaxBodySize, then restored.
+func DumpHTTPRequest(r *http.Request, opts ...Option) (map[string]string, error) {
+	return NewDefaultEncoder(opts...).DumpHTTPRequest(r)
+}
+
+// DumpHTTPResponse dumps an http.Response as a map[string]string. Headers are flattened, sensitive
+// headers are redacted and the body is captured up to Limits.MaxBodySize, then restored.
+func DumpHTTPResponse(r *http.Response, opts ...Option) (map[string]string, error) {
+	return NewDefaultEncoder(opts...).DumpHTTPResponse(r)
+}
+
+// DumpHTTPRequest dumps an http.Request as a map[string]string. Headers are flattened, sensitive
+// headers are redacted and the body is captured up to Limits.MaxBodySize, then restored.
+func (e *Encoder) DumpHTTPRequest(r *http.Request) (map[string]string, error) {
+	req := HTTPRequest{
+		Method:        r.Method,
+		Proto:         r.Proto,
+		Host:          r.Host,
+		Header:        e.flattenHeader(r.Header),
+		ContentLength: r.ContentLength,
+	}
+	if r.URL != nil {
+		req.URL = r.URL.String()
+	}
+	body, err := e.captureBody(&r.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = body
+	return e.ToStringMap(req)
+}
+
+// DumpHTTPResponse dumps an http.Response as a map[string]string. Headers are flattened, sensitive
+// headers are redacted and the body is captured up to Limits.MaxBodySize, then restored.
+func (e *Encoder) DumpHTTPResponse(r *http.Response) (map[string]string, error) {
+	resp := HTTPResponse{
+		Status:        r.Status,
+		StatusCode:    r.StatusCode,
+		Proto:         r.Proto,
+		Header:        e.flattenHeader(r.Header),
+		ContentLength: r.ContentLength,
+	}
+	body, err := e.captureBody(&r.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = body
+	return e.ToStringMap(resp)
+}
+
+func (e *Encoder) flattenHeader(h http.Header) map[string]string {
+	res := make(map[string]string, len(h))
+	for k, values := range h {
+		res[k] = strings.Join(values, ", ")
+	}
+	for _, k := range SensitiveHeaders {
+		if _, ok := res[http.CanonicalHeaderKey(k)]; ok {
+			res[http.CanonicalHeaderKey(k)] = e.maskValue(res[http.CanonicalHeaderKey(k)], "").(string)
+		}
+	}
+	return res
+}
+
+// captureBody reads the body up to the limit, and replaces it by a reader
+// which returns the whole body again, even if the read failed.
+func (e *Encoder) captureBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	limit := e.Limits.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	captured, err := io.ReadAll(io.LimitReader(*body, limit+1))
+	*body = readCloser{
+		Reader: io.MultiReader(bytes.NewReader(captured), *body),
+		Closer: *body,
+	}
+	if err != nil {
+		return "", err
+	}
+	if int64(len(captured)) > limit {
+		return string(captured[:limit]) + "...", nil
+	}
+	return string(captured), nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,88 @@
+package dump_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestDumpHTTPRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/api?id=1", strings.NewReader(`{"name":"foo"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Add("Accept", "text/plain")
+	req.Header.Add("Accept", "application/json")
+
+	m, err := dump.DumpHTTPRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"HTTPRequest.Method":               "POST",
+		"HTTPRequest.URL":                  "http://example.com/api?id=1",
+		"HTTPRequest.Proto":                "HTTP/1.1",
+		"HTTPRequest.Host":                 "example.com",
+		"HTTPRequest.Header.Content-Type":  "application/json",
+		"HTTPRequest.Header.Authorization": "***",
+		"HTTPRequest.Header.Accept":        "text/plain, application/json",
+		"HTTPRequest.ContentLength":        "14",
+		"HTTPRequest.Body":                 `{"name":"foo"}`,
+	}, m)
+
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"foo"}`, string(body))
+}
+
+func TestDumpHTTPResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Set-Cookie", "session=secret")
+	rec.WriteHeader(http.StatusOK)
+	rec.WriteString(strings.Repeat("a", 100))
+	resp := rec.Result()
+
+	e := dump.NewDefaultEncoder()
+	e.Limits.MaxBodySize = 10
+	m, err := e.DumpHTTPResponse(resp)
+	require.NoError(t, err)
+	assert.Equal(t, "200 OK", m["HTTPResponse.Status"])
+	assert.Equal(t, "***", m["HTTPResponse.Header.Set-Cookie"])
+	assert.Equal(t, "aaaaaaaaaa...", m["HTTPResponse.Body"])
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Len(t, body, 100)
+}
+
+var errBrokenBody = errors.New("broken body")
+
+// brokenBody returns its data, then fails
+type brokenBody struct {
+	data string
+}
+
+func (b *brokenBody) Read(p []byte) (int, error) {
+	if b.data == "" {
+		return 0, errBrokenBody
+	}
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	return n, nil
+}
+
+func TestDumpHTTPRequestBodyError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/api", &brokenBody{data: "partial"})
+
+	_, err := dump.DumpHTTPRequest(req)
+	assert.True(t, errors.Is(err, errBrokenBody))
+
+	body, err := io.ReadAll(req.Body)
+	assert.True(t, errors.Is(err, errBrokenBody))
+	assert.Equal(t, "partial", string(body))
+}
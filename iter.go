@@ -0,0 +1,21 @@
+package dump
+
+import "iter"
+
+// All returns an iterator over the leaves of i, as dumped by ToMap. The leaves are yielded in the
+// order they are dumped, without being stored, so that the iteration can stop early. The iteration
+// stops on the first error, use ToMap to get it. Panics raised while dumping are not recovered.
+// With TraversalBreadthFirst, the leaves are buffered until the value has been walked through.
+func (e *Encoder) All(i interface{}) iter.Seq2[string, interface{}] {
+	return func(yield func(string, interface{}) bool) {
+		if e.Traversal == TraversalBreadthFirst {
+			_ = e.breadthFirst(i, func(k string, v interface{}, _ int) bool {
+				return yield(k, v)
+			})
+			return
+		}
+		w := e.newDumpState()
+		w.emit = yield
+		_ = e.fdumpInterface(w, i, e.rootSegments(i))
+	}
+}
@@ -0,0 +1,40 @@
+package dump_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestEncoderAll(t *testing.T) {
+	type T struct {
+		A string
+		B []int
+		C map[string]string
+	}
+	a := T{A: "foo", B: []int{1, 2, 3}, C: map[string]string{"x": "1", "y": "2"}}
+
+	e := dump.NewDefaultEncoder()
+	e.ExtraFields.Len = true
+	want, err := e.ToMap(a)
+	require.NoError(t, err)
+
+	got := map[string]interface{}{}
+	for k, v := range e.All(a) {
+		got[k] = v
+	}
+	assert.Equal(t, want, got)
+
+	var keys []string
+	for k := range e.All(a) {
+		keys = append(keys, k)
+		if len(keys) == 2 {
+			break
+		}
+	}
+	assert.Len(t, keys, 2)
+	assert.Equal(t, []string{"T.A", "T.B.__Len__"}, keys)
+}
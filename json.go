@@ -0,0 +1,149 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// unmarshalJSON decodes data as json.Unmarshal does, except that the numbers are decoded as
+// int64 or uint64 when they are integers, so that large integers are not rounded through float64
+func unmarshalJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	switch t := v.(type) {
+	case *[]interface{}:
+		for i := range *t {
+			(*t)[i] = jsonNumbers((*t)[i])
+		}
+	case *map[string]interface{}:
+		for k := range *t {
+			(*t)[k] = jsonNumbers((*t)[k])
+		}
+	}
+	return nil
+}
+
+// jsonNumbers converts the json.Number values decoded with UseNumber, integers become int64 or
+// uint64, the other numbers float64. Integers overflowing uint64 are kept as json.Number.
+func jsonNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(t), 10, 64); err == nil {
+			return u
+		}
+		if !strings.ContainsAny(string(t), ".eE") {
+			return t
+		}
+		if f, err := strconv.ParseFloat(string(t), 64); err == nil {
+			return f
+		}
+		return t
+	case []interface{}:
+		for i := range t {
+			t[i] = jsonNumbers(t[i])
+		}
+	case map[string]interface{}:
+		for k := range t {
+			t[k] = jsonNumbers(t[k])
+		}
+	}
+	return v
+}
+
+const (
+	// DefaultMaxJSONSize is the default maximum size of the strings parsed with DeepJSON
+	DefaultMaxJSONSize = 1 << 20
+	// DefaultMaxJSONDepth is the default maximum nesting depth of the strings parsed with DeepJSON
+	DefaultMaxJSONDepth = 32
+)
+
+// maxJSONSize returns the maximum size of the strings parsed with DeepJSON, unlimited if negative
+func (e *Encoder) maxJSONSize() int {
+	if e.Limits.MaxJSONSize == 0 {
+		return DefaultMaxJSONSize
+	}
+	return e.Limits.MaxJSONSize
+}
+
+// deepJSONAllowed returns true if the string is within the DeepJSON limits, deeper strings are
+// dumped as is and larger ones are truncated to MaxJSONSize
+func (e *Encoder) deepJSONAllowed(s string) bool {
+	if maxSize := e.maxJSONSize(); maxSize > 0 && len(s) > maxSize {
+		return false
+	}
+	maxDepth := e.Limits.MaxJSONDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxJSONDepth
+	}
+	return maxDepth < 0 || jsonDepth(s) <= maxDepth
+}
+
+// jsonDepth returns the maximum nesting depth of the arrays and objects of a JSON document,
+// without parsing it
+func jsonDepth(s string) int {
+	var depth, max int
+	var inString, escaped bool
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '[' || c == '{':
+			depth++
+			if depth > max {
+				max = depth
+			}
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return max
+}
+
+// isJSONContainer returns true if b is a valid JSON array or object
+func isJSONContainer(b []byte) bool {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) == 0 || trimmed[0] != '[' && trimmed[0] != '{' {
+		return false
+	}
+	return json.Valid(trimmed)
+}
+
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
+
+// decodeBase64JSON decodes s if it is a base64 encoded JSON array or object
+func decodeBase64JSON(s string) ([]byte, bool) {
+	if len(s) < 4 {
+		return nil, false
+	}
+	for _, enc := range base64Encodings {
+		if b, err := enc.DecodeString(s); err == nil && isJSONContainer(b) {
+			return b, true
+		}
+	}
+	return nil, false
+}
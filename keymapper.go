@@ -0,0 +1,69 @@
+package dump
+
+import "strings"
+
+// KeyMapper transforms a dumped key, the KeyMappers of an encoder are applied in order by ViperKey
+type KeyMapper func(key string) string
+
+// StripPrefixMapper removes the prefix from the beginning of the keys
+func StripPrefixMapper(prefix string) KeyMapper {
+	return func(key string) string {
+		return strings.TrimPrefix(key, prefix)
+	}
+}
+
+// SeparatorMapper replaces each separator in the keys with another one
+func SeparatorMapper(old, new string) KeyMapper {
+	return func(key string) string {
+		if old == "" {
+			return key
+		}
+		return strings.Replace(key, old, new, -1)
+	}
+}
+
+// LowerCaseMapper turns the keys to lowercase
+func LowerCaseMapper() KeyMapper {
+	return strings.ToLower
+}
+
+// UpperCaseMapper turns the keys to uppercase
+func UpperCaseMapper() KeyMapper {
+	return strings.ToUpper
+}
+
+// keyMappers returns the KeyMappers of the encoder, or the default pipeline computing Viper keys:
+// the prefix is stripped, the separators are replaced with dots and the keys are lowercased
+func (e *Encoder) keyMappers() []KeyMapper {
+	if e.KeyMappers != nil {
+		return e.KeyMappers
+	}
+	var mappers []KeyMapper
+	if e.Prefix != "" {
+		mappers = append(mappers, StripPrefixMapper(e.Prefix+e.Separator))
+	}
+	return append(mappers, SeparatorMapper(e.Separator, "."), LowerCaseMapper())
+}
+
+// ViperKey returns the Viper key of a dumped key, computed with the KeyMappers of the encoder
+func (e *Encoder) ViperKey(s string) string {
+	for _, m := range e.keyMappers() {
+		s = m(s)
+	}
+	return s
+}
+
+// ViperKeys returns the Viper keys of the keys of m, such as the result of ToStringMap,
+// indexed by the dumped keys
+func (e *Encoder) ViperKeys(m map[string]string) map[string]string {
+	mappers := e.keyMappers()
+	res := make(map[string]string, len(m))
+	for k := range m {
+		s := k
+		for _, mapper := range mappers {
+			s = mapper(s)
+		}
+		res[k] = s
+	}
+	return res
+}
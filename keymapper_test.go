@@ -0,0 +1,46 @@
+package dump_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestViperKeys(t *testing.T) {
+	type T struct {
+		A string
+		B struct {
+			InsideB string
+		}
+	}
+	var a T
+	a.A = "value A"
+	a.B.InsideB = "value B"
+
+	e := dump.NewDefaultEncoder()
+	e.DisableTypePrefix = true
+	e.Separator = "_"
+	e.Prefix = "MYSTRUCT"
+	e.Formatters = []dump.KeyFormatterFunc{dump.WithDefaultUpperCaseFormatter()}
+	envs, err := e.ToStringMap(a)
+	require.NoError(t, err)
+
+	assert.Equal(t, "b.insideb", e.ViperKey("MYSTRUCT_B_INSIDEB"))
+	assert.Equal(t, map[string]string{
+		"MYSTRUCT_A":         "a",
+		"MYSTRUCT_B_INSIDEB": "b.insideb",
+	}, e.ViperKeys(envs))
+
+	e.KeyMappers = []dump.KeyMapper{
+		dump.StripPrefixMapper("MYSTRUCT_"),
+		dump.SeparatorMapper("_", "/"),
+		dump.UpperCaseMapper(),
+	}
+	assert.Equal(t, "B/INSIDEB", e.ViperKey("MYSTRUCT_B_INSIDEB"))
+
+	e.KeyMappers = []dump.KeyMapper{}
+	assert.Equal(t, "MYSTRUCT_B_INSIDEB", e.ViperKey("MYSTRUCT_B_INSIDEB"))
+}
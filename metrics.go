@@ -0,0 +1,48 @@
+package dump
+
+import "reflect"
+
+// ToMetricMap dumps the numeric leaves of i, with keys valid for metric systems such as Prometheus
+// or Graphite, as produced by PrometheusSanitizer. Bool leaves are dumped as 1 or 0, the other
+// leaves are ignored. An error wrapping ErrKeyCollision is returned if distinct keys are sanitized
+// to the same metric key.
+func (e *Encoder) ToMetricMap(i interface{}) (map[string]float64, error) {
+	m, err := e.ToMap(i)
+	if err != nil {
+		return nil, err
+	}
+	metrics := make(map[string]float64, len(m))
+	for k, v := range m {
+		if f, ok := metricValue(v); ok {
+			metrics[k] = f
+		}
+	}
+	return sanitizeKeys(metrics, e.Separator, PrometheusSanitizer())
+}
+
+// metricKey replaces the characters of s not matching [a-zA-Z0-9_] with underscores. A leading digit
+// is preceded by an underscore.
+func metricKey(s string) string {
+	return replaceInvalid(s, func(r rune) bool {
+		return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+	}, '_')
+}
+
+// metricValue converts the numeric and bool values to float64
+func metricValue(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
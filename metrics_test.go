@@ -0,0 +1,45 @@
+package dump_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestToMetricMap(t *testing.T) {
+	type Stats struct {
+		Name     string
+		Requests map[string]int
+		Ratio    float64
+		Healthy  bool
+	}
+	a := Stats{
+		Name:     "api",
+		Requests: map[string]int{"GET /users": 12, "POST /users": 3},
+		Ratio:    0.25,
+		Healthy:  true,
+	}
+
+	e := dump.NewDefaultEncoder()
+	e.Formatters = nil
+	m, err := e.ToMetricMap(a)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{
+		"Stats_Requests_GET__users":  12,
+		"Stats_Requests_POST__users": 3,
+		"Stats_Ratio":                0.25,
+		"Stats_Healthy":              1,
+	}, m)
+
+	m, err = e.ToMetricMap([]int{4})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"_0": 4}, m)
+
+	_, err = e.ToMetricMap(map[string]int{"a.b": 1, "a-b": 2})
+	assert.True(t, errors.Is(err, dump.ErrKeyCollision))
+	assert.EqualError(t, err, "key collision: a-b and a.b are both sanitized to a_b")
+}
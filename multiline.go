@@ -0,0 +1,52 @@
+package dump
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultilineMode is the way values containing newlines are rendered
+type MultilineMode int
+
+const (
+	// MultilineRaw keeps the newlines as they are
+	MultilineRaw MultilineMode = iota
+	// MultilineEscape replaces the newlines with their escaped form \n
+	MultilineEscape
+	// MultilineIndent indents the continuation lines under the key in Fdump and Sdump outputs
+	MultilineIndent
+	// MultilineSplit splits the values into one key per line: <key>.line0, <key>.line1...
+	MultilineSplit
+)
+
+// MultilineIndentation is the indentation of the continuation lines with MultilineIndent
+const MultilineIndentation = "  "
+
+var newlineEscaper = strings.NewReplacer("\r", `\r`, "\n", `\n`)
+
+// multiline applies the multiline mode on a string value, stored in res under the key k
+func (e *Encoder) multiline(res map[string]string, k, v string) {
+	if !strings.ContainsAny(v, "\r\n") {
+		res[k] = v
+		return
+	}
+	switch e.Multiline {
+	case MultilineEscape:
+		res[k] = newlineEscaper.Replace(v)
+	case MultilineSplit:
+		lines := strings.Split(strings.ReplaceAll(v, "\r\n", "\n"), "\n")
+		for i, l := range lines {
+			res[fmt.Sprintf("%s%sline%d", k, e.Separator, i)] = l
+		}
+	default:
+		res[k] = v
+	}
+}
+
+// indent indents the continuation lines of a value written by Fdump and Sdump
+func (e *Encoder) indent(v string) string {
+	if e.Multiline != MultilineIndent {
+		return v
+	}
+	return strings.ReplaceAll(v, "\n", "\n"+MultilineIndentation)
+}
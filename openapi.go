@@ -0,0 +1,167 @@
+package dump
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIExample returns the OpenAPI `example` YAML fragment of i, such as an instance of a request
+// or response body. The example is nested as the JSON encoding of i: its keys are the json tag names
+// of the fields, the fields of the embedded structs are promoted and the empty slices and maps are
+// kept as [] and {}.
+func (e *Encoder) OpenAPIExample(i interface{}) ([]byte, error) {
+	node, err := e.exampleNode(i)
+	if err != nil {
+		return nil, err
+	}
+	return marshalYAML(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "example"}, node,
+	}})
+}
+
+// OpenAPIExamples returns the OpenAPI `examples` YAML fragment of the named examples, sorted by name
+func (e *Encoder) OpenAPIExamples(examples map[string]interface{}) ([]byte, error) {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	e.sortKeys(names, nil)
+
+	list := &yaml.Node{Kind: yaml.MappingNode}
+	for _, name := range names {
+		node, err := e.exampleNode(examples[name])
+		if err != nil {
+			return nil, err
+		}
+		list.Content = append(list.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+			&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: "value"}, node}},
+		)
+	}
+	return marshalYAML(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "examples"}, list,
+	}})
+}
+
+// exampleNode dumps i and nests its leaves in a YAML node, following the struct fields, the map
+// keys and the array indexes of their paths
+func (e *Encoder) exampleNode(i interface{}) (node *yaml.Node, err error) {
+	c := e.Clone()
+	c.ExtraFields.UseJSONTag = true
+	c.ExtraFields.Len = false
+	c.ExtraFields.Type = false
+	c.ExtraFields.DetailedStruct = false
+	c.ExtraFields.DetailedMap = false
+	c.ExtraFields.DetailedArray = false
+	c.ExtraFields.MapKeyType = false
+	c.ExtraFields.InterfaceType = false
+	c.Formatters = nil
+	c.ContextFormatters = nil
+	c.TagPriority = nil
+	c.KeyTag = ""
+	c.Prefix = ""
+	c.Suffix = ""
+	c.RootName = ""
+	c.IndexFormatter = nil
+	c.ArrayJSONNotation = false
+	c.SummaryOnly = false
+	c.PromoteEmbedded = true
+	if !c.DisableRecover {
+		defer recoverError(&err)
+	}
+
+	root := &yaml.Node{}
+	w := c.newDumpState()
+	w.emit = func(string, interface{}) bool { return true }
+	w.leaf = func(_ string, v interface{}, roots []segment) {
+		c.setExampleValue(exampleChild(root, roots), v)
+	}
+	w.onContainer = func(roots []segment, kind detailKind) {
+		if n := exampleChild(root, roots); n.Kind == 0 {
+			n.Kind = yaml.MappingNode
+			if kind == detailArray {
+				n.Kind = yaml.SequenceNode
+			}
+		}
+	}
+	if err := c.fdumpInterface(w, i, c.rootSegments(i)); err != nil {
+		return nil, err
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	if root.Kind == 0 {
+		root.Kind = yaml.MappingNode
+	}
+	return root, nil
+}
+
+// exampleChild returns the node of the path, the intermediate mappings and sequences are created
+func exampleChild(node *yaml.Node, roots []segment) *yaml.Node {
+	for _, s := range roots {
+		switch s.kind {
+		case FieldSegment, MapKeySegment:
+			node.Kind = yaml.MappingNode
+			var child *yaml.Node
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == s.name {
+					child = node.Content[j+1]
+				}
+			}
+			if child == nil {
+				child = &yaml.Node{}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: s.name}, child)
+			}
+			node = child
+		case IndexSegment:
+			node.Kind = yaml.SequenceNode
+			for len(node.Content) <= s.index {
+				node.Content = append(node.Content, &yaml.Node{})
+			}
+			node = node.Content[s.index]
+		}
+	}
+	return node
+}
+
+// setExampleValue sets the scalar node of a leaf, numbers and bools are kept as is
+func (e *Encoder) setExampleValue(node *yaml.Node, v interface{}) {
+	node.Kind = yaml.ScalarNode
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		node.Tag = "!!bool"
+		node.Value = strconv.FormatBool(rv.Bool())
+		return
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		node.Tag = "!!int"
+		node.Value = e.printValue(v)
+		return
+	case reflect.Float32, reflect.Float64:
+		if !isNonFinite(v) {
+			node.Tag = "!!float"
+			node.Value = e.printValue(v)
+			return
+		}
+	}
+	node.Tag = "!!str"
+	node.Value = e.printValue(v)
+}
+
+// marshalYAML encodes the node with a 2 spaces indentation
+func marshalYAML(node *yaml.Node) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
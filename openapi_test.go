@@ -0,0 +1,80 @@
+package dump_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestOpenAPIExample(t *testing.T) {
+	type Address struct {
+		City string `json:"city"`
+	}
+	type User struct {
+		ID        int               `json:"id"`
+		Name      string            `json:"name"`
+		Admin     bool              `json:"admin"`
+		Score     float64           `json:"score"`
+		Addresses []Address         `json:"addresses"`
+		Labels    map[string]string `json:"labels"`
+	}
+	u := User{
+		ID:        1,
+		Name:      "John",
+		Admin:     true,
+		Score:     9.5,
+		Addresses: []Address{{City: "Paris"}, {City: "Lyon"}},
+		Labels:    map[string]string{"team": "core"},
+	}
+
+	e := dump.NewDefaultEncoder()
+	b, err := e.OpenAPIExample(u)
+	require.NoError(t, err)
+	assert.Equal(t, `example:
+  id: 1
+  name: John
+  admin: true
+  score: 9.5
+  addresses:
+    - city: Paris
+    - city: Lyon
+  labels:
+    team: core
+`, string(b))
+
+	b, err = e.OpenAPIExamples(map[string]interface{}{
+		"simple": Address{City: "Nantes"},
+		"number": "42",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `examples:
+  number:
+    value: "42"
+  simple:
+    value:
+      city: Nantes
+`, string(b))
+}
+
+func TestOpenAPIExampleEmbedded(t *testing.T) {
+	type Base struct {
+		ID int `json:"id"`
+	}
+	type Item struct {
+		Base
+		Tags   []string          `json:"tags"`
+		Labels map[string]string `json:"labels"`
+	}
+
+	e := dump.NewDefaultEncoder()
+	b, err := e.OpenAPIExample(Item{Base: Base{ID: 1}, Tags: []string{}, Labels: map[string]string{}})
+	require.NoError(t, err)
+	assert.Equal(t, `example:
+  id: 1
+  tags: []
+  labels: {}
+`, string(b))
+}
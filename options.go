@@ -0,0 +1,66 @@
+package dump
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option configures an Encoder
+type Option func(e *Encoder)
+
+// SensitiveKeys are the key patterns redacted by WithSecurityDefaults
+var SensitiveKeys = []string{
+	"*password*",
+	"*passwd*",
+	"*secret*",
+	"*token*",
+	"*apikey*",
+	"*api_key*",
+	"*authorization*",
+	"*cookie*",
+	"*credential*",
+	"*private*key*",
+}
+
+// WithSecurityDefaults configures the encoder for production logging: the values of the
+// SensitiveKeys are redacted, long strings are truncated and the depth of the dump is limited.
+func WithSecurityDefaults() Option {
+	return func(e *Encoder) {
+		e.RedactKeys(SensitiveKeys...)
+		e.Limits.MaxStringLength = 1024
+		e.Limits.MaxDepth = 32
+	}
+}
+
+// PointerPlaceholder replaces the memory addresses with the RedactPointers option
+const PointerPlaceholder = "<pointer>"
+
+// CanonicalFloatPrecision is the number of decimals of the float values set by WithCanonicalOutput
+const CanonicalFloatPrecision = 6
+
+// WithCanonicalOutput configures the encoder to produce byte-identical dumps across runs, as
+// needed by golden-file tests: times are rendered in UTC with the RFC3339 layout, floats with
+// CanonicalFloatPrecision decimals and memory addresses are redacted. Fdump and Sdump always
+// sort the keys.
+func WithCanonicalOutput() Option {
+	return func(e *Encoder) {
+		e.TimeUTC = true
+		e.TimeFormat = time.RFC3339
+		e.UnixTime = UnixTimeDisabled
+		e.FloatPrecision = CanonicalFloatPrecision
+		e.CanonicalNumbers = true
+		e.RedactPointers = true
+	}
+}
+
+// WithPrefix sets the Prefix of the keys
+func WithPrefix(prefix string) Option {
+	return func(e *Encoder) {
+		e.Prefix = prefix
+	}
+}
+
+// DefaultDocumentHeader is a DocumentHeader such as "--- 2006-01-02T15:04:05Z main.T ---"
+func DefaultDocumentHeader(i interface{}) string {
+	return fmt.Sprintf("--- %s %T ---", time.Now().Format(time.RFC3339), i)
+}
@@ -0,0 +1,74 @@
+package dump
+
+import "sort"
+
+// KeyOrder is the order of the keys written by Fdump and Sdump
+type KeyOrder int
+
+const (
+	// KeyOrderNatural sorts the keys comparing their numbers numerically, so that "Items2" is
+	// written before "Items10"
+	KeyOrderNatural KeyOrder = iota
+	// KeyOrderLexical sorts the keys lexicographically
+	KeyOrderLexical
+)
+
+// sortKeys sorts the keys in the KeyOrder of the encoder. If levels is not nil, the keys are sorted
+// by level first.
+func (e *Encoder) sortKeys(keys []string, levels map[string]int) {
+	less := naturalLess
+	if e.KeyOrder == KeyOrderLexical {
+		less = func(a, b string) bool { return a < b }
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if levels != nil && levels[keys[i]] != levels[keys[j]] {
+			return levels[keys[i]] < levels[keys[j]]
+		}
+		return less(keys[i], keys[j])
+	})
+}
+
+// naturalLess compares two strings, the runs of digits being compared numerically
+func naturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			si, sj := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			na, nb := trimZeros(a[si:i]), trimZeros(b[sj:j])
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			// Same numbers, the one with less leading zeros first
+			if i-si != j-sj {
+				return i-si < j-sj
+			}
+			continue
+		}
+		if a[i] != b[j] {
+			return a[i] < b[j]
+		}
+		i++
+		j++
+	}
+	return len(a)-i < len(b)-j
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func trimZeros(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
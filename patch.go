@@ -0,0 +1,307 @@
+package dump
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnknownKey is returned by Patch for the overrides which don't address any leaf of the target
+var ErrUnknownKey = errors.New("unknown key")
+
+// timeLayouts are the layouts used to parse time.Time overrides, after the TimeFormat of the encoder
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999 -0700 MST"}
+
+// patcher holds the state of a Patch
+type patcher struct {
+	// key maps the dumped keys to the keys of the overrides
+	key       func(k string) string
+	overrides map[string]string
+	applied   map[string]bool
+	visiting  map[uintptr]bool
+}
+
+// Patch sets the leaves of target, a non-nil pointer, addressed by the keys of overrides, as they are
+// dumped by the encoder. The values are converted to the type of the leaves: strings, numbers,
+// bools, durations, times and encoding.TextUnmarshaler implementations. Nil pointers are allocated
+// and map entries are added as needed, the elements of arrays and slices must exist. The leaves are
+// set even if an error is returned, it wraps ErrUnknownKey for the overrides which don't address
+// any leaf.
+//
+// Combined with ToStringMap, it overrides the defaults of a configuration with flags or
+// environment variables.
+func (e *Encoder) Patch(target interface{}, overrides map[string]string) error {
+	return e.patch(target, overrides, func(k string) string { return k })
+}
+
+func (e *Encoder) patch(target interface{}, overrides map[string]string, key func(k string) string) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("dump: Patch needs a non-nil pointer, got %T", target)
+	}
+	p := &patcher{key: key, overrides: overrides, applied: map[string]bool{}, visiting: map[uintptr]bool{}}
+	if err := e.patchValue(p, v.Elem(), e.rootSegments(target)); err != nil {
+		return err
+	}
+
+	var unknown []string
+	for k := range overrides {
+		if !p.applied[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: %s", ErrUnknownKey, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Patch sets the leaves of target addressed by the keys of overrides, see Encoder.Patch
+func Patch(target interface{}, overrides map[string]string, formatters ...KeyFormatterFunc) error {
+	return newEncoder(nil, formatters).Patch(target, overrides)
+}
+
+func (e *Encoder) patchValue(p *patcher, v reflect.Value, roots []segment) error {
+	if isPatchLeaf(v.Type()) {
+		if len(roots) == 0 {
+			return nil
+		}
+		k := p.key(e.fullKey(roots))
+		s, ok := p.overrides[k]
+		if !ok {
+			return nil
+		}
+		if err := e.setLeaf(v, s); err != nil {
+			return e.newError(roots, v.Type(), err)
+		}
+		p.applied[k] = true
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			if p.visiting[v.Pointer()] {
+				return nil
+			}
+			p.visiting[v.Pointer()] = true
+			defer delete(p.visiting, v.Pointer())
+			return e.patchValue(p, v.Elem(), roots)
+		}
+		if !p.wants(p.key(e.keyPrefix(roots))) {
+			return nil
+		}
+		n := reflect.New(v.Type().Elem())
+		applied := len(p.applied)
+		if err := e.patchValue(p, n.Elem(), roots); err != nil {
+			return err
+		}
+		if len(p.applied) > applied {
+			v.Set(n)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		// The dynamic value is not addressable, it is patched on a copy
+		c := reflect.New(v.Elem().Type()).Elem()
+		c.Set(v.Elem())
+		applied := len(p.applied)
+		if err := e.patchValue(p, c, roots); err != nil {
+			return err
+		}
+		if len(p.applied) > applied {
+			v.Set(c)
+		}
+	case reflect.Struct:
+		return e.patchFields(p, v, e.structPrefix(roots, v.Type()))
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.patchValue(p, v.Index(i), e.indexRoots(roots, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		return e.patchMap(p, v, roots)
+	}
+	return nil
+}
+
+func (e *Encoder) patchFields(p *patcher, s reflect.Value, roots []segment) error {
+	for _, fp := range e.structPlan(s.Type()) {
+		fv := s.Field(fp.index)
+		if !fv.CanSet() || fp.omit {
+			continue
+		}
+		if e.FieldFilter != nil && !e.FieldFilter(s.Type(), fp.field) {
+			continue
+		}
+		if fp.inline {
+			if fv.Kind() == reflect.Struct {
+				if err := e.patchFields(p, fv, roots); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		field := fp.field
+		if err := e.patchValue(p, fv, append(roots[:len(roots):len(roots)], segment{name: fp.name, kind: FieldSegment, field: &field})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchMap patches the entries of a map, the map values are not addressable so they are patched
+// on copies. The entries of maps with string keys are added for the overrides addressing them.
+func (e *Encoder) patchMap(p *patcher, m reflect.Value, roots []segment) error {
+	t := m.Type()
+	for _, k := range m.MapKeys() {
+		croots := append(roots[:len(roots):len(roots)], segment{name: e.mapKey(k), kind: MapKeySegment})
+		c := reflect.New(t.Elem()).Elem()
+		c.Set(m.MapIndex(k))
+		applied := len(p.applied)
+		if c.Kind() == reflect.Struct {
+			croots = e.typePrefix(croots, c.Type(), mapValuePosition)
+		}
+		if err := e.patchValue(p, c, croots); err != nil {
+			return err
+		}
+		if len(p.applied) > applied {
+			m.SetMapIndex(k, c)
+		}
+	}
+
+	if t.Key().Kind() != reflect.String || !isPatchLeaf(t.Elem()) {
+		return nil
+	}
+	prefix := p.key(e.keyPrefix(roots))
+	for k, s := range p.overrides {
+		name := strings.TrimPrefix(k, prefix)
+		if p.applied[k] || !strings.HasPrefix(k, prefix) || name == "" || e.Separator != "" && strings.Contains(name, e.Separator) {
+			continue
+		}
+		c := reflect.New(t.Elem()).Elem()
+		if err := e.setLeaf(c, s); err != nil {
+			return e.newError(append(roots, segment{name: name, kind: MapKeySegment}), c.Type(), err)
+		}
+		if m.IsNil() {
+			m.Set(reflect.MakeMap(t))
+		}
+		m.SetMapIndex(reflect.ValueOf(name).Convert(t.Key()), c)
+		p.applied[k] = true
+	}
+	return nil
+}
+
+// keyPrefix returns the beginning of the keys of the leaves dumped under roots
+func (e *Encoder) keyPrefix(roots []segment) string {
+	var k string
+	if e.Prefix != "" {
+		k = e.Prefix + e.Separator
+	}
+	if len(roots) > 0 {
+		k += e.formatKey(roots) + e.Separator
+	}
+	return k
+}
+
+// wants tells if an override not applied yet starts with prefix
+func (p *patcher) wants(prefix string) bool {
+	for k := range p.overrides {
+		if !p.applied[k] && strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isPatchLeaf tells if the values of type t are set from a single string by Patch
+func isPatchLeaf(t reflect.Type) bool {
+	if t == timeType || t == durationType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// setLeaf converts s to the type of v and sets it
+func (e *Encoder) setLeaf(v reflect.Value, s string) error {
+	switch v.Type() {
+	case timeType:
+		layouts := timeLayouts
+		if e.TimeFormat != "" {
+			layouts = append([]string{e.TimeFormat}, layouts...)
+		}
+		var err error
+		for _, l := range layouts {
+			var t time.Time
+			if t, err = time.Parse(l, s); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return err
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		switch {
+		case e.BoolFormat.True != "" && s == e.BoolFormat.True:
+			v.SetBool(true)
+		case e.BoolFormat.False != "" && s == e.BoolFormat.False:
+			v.SetBool(false)
+		default:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package dump_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestPatch(t *testing.T) {
+	type Database struct {
+		Host string
+		Port int
+	}
+	type Config struct {
+		Name     string
+		Debug    bool
+		Ratio    float64
+		Timeout  time.Duration
+		Since    time.Time
+		Database Database
+		Replica  *Database
+		Tags     []string
+		Labels   map[string]string
+	}
+	cfg := Config{
+		Name:     "api",
+		Timeout:  time.Second,
+		Database: Database{Host: "localhost", Port: 5432},
+		Tags:     []string{"a", "b"},
+	}
+
+	e := dump.NewDefaultEncoder()
+	err := e.Patch(&cfg, map[string]string{
+		"Config.Debug":         "true",
+		"Config.Ratio":         "0.5",
+		"Config.Timeout":       "1m30s",
+		"Config.Since":         "2020-01-02T03:04:05Z",
+		"Config.Database.Port": "5433",
+		"Config.Replica.Host":  "replica",
+		"Config.Tags.Tags1":    "c",
+		"Config.Labels.env":    "prod",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Name:     "api",
+		Debug:    true,
+		Ratio:    0.5,
+		Timeout:  90 * time.Second,
+		Since:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Database: Database{Host: "localhost", Port: 5433},
+		Replica:  &Database{Host: "replica"},
+		Tags:     []string{"a", "c"},
+		Labels:   map[string]string{"env": "prod"},
+	}, cfg)
+
+	// Dumping and patching are symmetric
+	m, err := e.ToStringMap(cfg)
+	require.NoError(t, err)
+	patched := Config{Tags: make([]string, 2)}
+	require.NoError(t, e.Patch(&patched, m))
+	assert.Equal(t, cfg, patched)
+
+	err = e.Patch(&cfg, map[string]string{"Config.Database.Port": "http"})
+	var dumpErr *dump.Error
+	require.True(t, errors.As(err, &dumpErr))
+	assert.Equal(t, "Config.Database.Port", dumpErr.Key)
+
+	err = e.Patch(&cfg, map[string]string{"Config.Unknown": "1", "Config.Name": "web"})
+	assert.True(t, errors.Is(err, dump.ErrUnknownKey))
+	assert.Equal(t, "web", cfg.Name)
+
+	assert.Error(t, e.Patch(cfg, nil))
+}
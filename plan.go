@@ -0,0 +1,153 @@
+package dump
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldPlan is the precomputed dump configuration of a struct field
+type fieldPlan struct {
+	index     int
+	field     reflect.StructField
+	name      string
+	tag       dumpTag
+	omit      bool
+	omitEmpty bool
+	inline    bool
+}
+
+// encoderCache caches the struct plans and the formatted keys of an encoder whose configuration
+// doesn't change anymore, such as the one of a TypedEncoder
+type encoderCache struct {
+	plans     sync.Map // reflect.Type -> []fieldPlan
+	dominants sync.Map // reflect.Type -> map[string]string
+	keys      sync.Map // string -> string
+}
+
+// structPlan returns the plans of the fields of the struct type
+func (e *Encoder) structPlan(t reflect.Type) []fieldPlan {
+	if e.cache != nil {
+		if p, ok := e.cache.plans.Load(t); ok {
+			return p.([]fieldPlan)
+		}
+	}
+	plan := make([]fieldPlan, t.NumField())
+	for i := range plan {
+		field := t.Field(i)
+		omit, omitEmpty := e.fieldOmission(field)
+		plan[i] = fieldPlan{
+			index:     i,
+			field:     field,
+			name:      e.fieldName(field),
+			tag:       parseDumpTag(field.Tag.Get("dump")),
+			omit:      omit,
+			omitEmpty: omitEmpty,
+			inline:    e.inlineField(field),
+		}
+	}
+	if e.cache != nil {
+		e.cache.plans.Store(t, plan)
+	}
+	return plan
+}
+
+// leafKey returns the formatted key of the path. With a cache, the keys of the paths which only
+// hold struct fields are cached, so that the size of the cache is bounded by the dumped types.
+func (e *Encoder) leafKey(roots []segment) string {
+	if e.cache == nil || len(e.ContextFormatters) > 0 {
+		return e.formatKey(roots)
+	}
+	var sb strings.Builder
+	for _, s := range roots {
+		if s.kind == MapKeySegment || s.kind == IndexSegment {
+			return e.formatKey(roots)
+		}
+		sb.WriteString(s.name)
+		sb.WriteByte(0)
+		sb.WriteByte(byte(s.kind))
+	}
+	id := sb.String()
+	if k, ok := e.cache.keys.Load(id); ok {
+		return k.(string)
+	}
+	k := e.formatKey(roots)
+	e.cache.keys.Store(id, k)
+	return k
+}
+
+// dominantFields returns, for a struct type with inlined fields, the index path of the field
+// dumped under each name. As with encoding/json, the shallowest field wins, and the names of
+// several fields at the same depth are not dumped. It returns nil if no field is inlined.
+func (e *Encoder) dominantFields(t reflect.Type) map[string]string {
+	if e.cache != nil {
+		if d, ok := e.cache.dominants.Load(t); ok {
+			return d.(map[string]string)
+		}
+	}
+	var dominants map[string]string
+	for _, p := range e.structPlan(t) {
+		if p.inline {
+			dominants = e.computeDominantFields(t)
+			break
+		}
+	}
+	if e.cache != nil {
+		e.cache.dominants.Store(t, dominants)
+	}
+	return dominants
+}
+
+func (e *Encoder) computeDominantFields(t reflect.Type) map[string]string {
+	type level struct {
+		t     reflect.Type
+		index []int
+	}
+	dominants := map[string]string{}
+	visited := map[reflect.Type]bool{}
+	current := []level{{t: t}}
+	for len(current) > 0 {
+		var next []level
+		count := map[string]int{}
+		found := map[string]string{}
+		for _, l := range current {
+			if visited[l.t] {
+				continue
+			}
+			visited[l.t] = true
+			for _, p := range e.structPlan(l.t) {
+				if p.omit || p.field.PkgPath != "" && !p.field.Anonymous {
+					continue
+				}
+				index := append(append([]int(nil), l.index...), p.index)
+				ft := p.field.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if p.inline && ft.Kind() == reflect.Struct {
+					next = append(next, level{t: ft, index: index})
+					continue
+				}
+				if _, shadowed := dominants[p.name]; shadowed {
+					continue
+				}
+				count[p.name]++
+				found[p.name] = indexKey(index)
+			}
+		}
+		for name, n := range count {
+			if n > 1 {
+				// Ambiguous names are not dumped
+				found[name] = ""
+			}
+			dominants[name] = found[name]
+		}
+		current = next
+	}
+	return dominants
+}
+
+func indexKey(index []int) string {
+	return fmt.Sprint(index)
+}
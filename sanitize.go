@@ -0,0 +1,120 @@
+package dump
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sanitizer rewrites a dumped key for a target system, such as a metric name or a Consul path.
+// It receives the key and the separator of its segments.
+type Sanitizer func(key, separator string) string
+
+// EnvSanitizer produces environment variable names: the keys are uppercased, the separators and
+// the characters not matching [A-Z0-9_] are replaced with underscores
+func EnvSanitizer() Sanitizer {
+	return func(key, separator string) string {
+		return replaceInvalid(strings.ToUpper(key), func(r rune) bool {
+			return r == '_' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+		}, '_')
+	}
+}
+
+// PrometheusSanitizer produces metric names valid for Prometheus and Graphite, matching
+// [a-zA-Z_][a-zA-Z0-9_]*: the separators and the other characters are replaced with underscores
+func PrometheusSanitizer() Sanitizer {
+	return func(key, separator string) string {
+		return metricKey(key)
+	}
+}
+
+// ConsulSanitizer produces Consul KV paths: the separators are replaced with slashes and the
+// characters not matching [a-zA-Z0-9_.-] are replaced with underscores
+func ConsulSanitizer() Sanitizer {
+	return func(key, separator string) string {
+		segments := splitKey(key, separator)
+		for i, s := range segments {
+			segments[i] = strings.Map(func(r rune) rune {
+				if r == '_' || r == '.' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+					return r
+				}
+				return '_'
+			}, s)
+		}
+		return strings.Join(segments, "/")
+	}
+}
+
+// DNSSanitizer produces DNS names: each segment becomes a lowercase label made of letters, digits
+// and hyphens, at most 63 characters long, and the labels are separated with dots
+func DNSSanitizer() Sanitizer {
+	return func(key, separator string) string {
+		var labels []string
+		for _, s := range splitKey(key, separator) {
+			l := strings.Trim(replaceInvalid(strings.ToLower(s), func(r rune) bool {
+				return r == '-' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9'
+			}, '-'), "-")
+			if len(l) > 63 {
+				l = strings.TrimRight(l[:63], "-")
+			}
+			if l != "" {
+				labels = append(labels, l)
+			}
+		}
+		return strings.Join(labels, ".")
+	}
+}
+
+// ToSanitizedMap dumps i like ToStringMap, then rewrites the keys with the sanitizer. An error
+// wrapping ErrKeyCollision is returned if distinct keys are sanitized to the same key.
+func (e *Encoder) ToSanitizedMap(i interface{}, s Sanitizer) (map[string]string, error) {
+	m, err := e.ToStringMap(i)
+	if err != nil {
+		return nil, err
+	}
+	return sanitizeKeys(m, e.Separator, s)
+}
+
+// sanitizeKeys rewrites the keys of m with the sanitizer and detects the collisions
+func sanitizeKeys[V any](m map[string]V, separator string, s Sanitizer) (map[string]V, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make(map[string]V, len(m))
+	origins := make(map[string]string, len(m))
+	for _, k := range keys {
+		sk := s(k, separator)
+		if o, ok := origins[sk]; ok {
+			return nil, fmt.Errorf("%w: %s and %s are both sanitized to %s", ErrKeyCollision, o, k, sk)
+		}
+		origins[sk] = k
+		res[sk] = m[k]
+	}
+	return res, nil
+}
+
+// replaceInvalid replaces the runes of s which are not valid with r. A leading digit is preceded
+// by r.
+func replaceInvalid(s string, valid func(rune) bool, r rune) string {
+	s = strings.Map(func(c rune) rune {
+		if valid(c) {
+			return c
+		}
+		return r
+	}, s)
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		s = string(r) + s
+	}
+	return s
+}
+
+// splitKey splits a key on the separator, if any
+func splitKey(key, separator string) []string {
+	if separator == "" {
+		return []string{key}
+	}
+	return strings.Split(key, separator)
+}
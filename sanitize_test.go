@@ -0,0 +1,56 @@
+package dump_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestToSanitizedMap(t *testing.T) {
+	type Service struct {
+		Name   string
+		Labels map[string]string
+	}
+	a := Service{
+		Name:   "api",
+		Labels: map[string]string{"app.kubernetes.io/name": "api"},
+	}
+
+	e := dump.NewDefaultEncoder()
+	e.Formatters = nil
+
+	m, err := e.ToSanitizedMap(a, dump.EnvSanitizer())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"SERVICE_NAME":                          "api",
+		"SERVICE_LABELS_APP_KUBERNETES_IO_NAME": "api",
+	}, m)
+
+	m, err = e.ToSanitizedMap(a, dump.PrometheusSanitizer())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Service_Name":                          "api",
+		"Service_Labels_app_kubernetes_io_name": "api",
+	}, m)
+
+	m, err = e.ToSanitizedMap(a, dump.ConsulSanitizer())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Service/Name":                          "api",
+		"Service/Labels/app/kubernetes/io_name": "api",
+	}, m)
+
+	m, err = e.ToSanitizedMap(map[string]string{"My Service": "api", "2nd_Service--": "db"}, dump.DNSSanitizer())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"my-service":  "api",
+		"2nd-service": "db",
+	}, m)
+
+	_, err = e.ToSanitizedMap(map[string]string{"a b": "1", "a-b": "2"}, dump.EnvSanitizer())
+	assert.True(t, errors.Is(err, dump.ErrKeyCollision))
+}
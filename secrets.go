@@ -0,0 +1,51 @@
+package dump
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	jwtRegexp        = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	awsKeyRegexp     = regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA)[0-9A-Z]{16}\b`)
+	bearerRegexp     = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	pemRegexp        = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----[\s\S]*?-----END [A-Z0-9 ]+-----`)
+	creditCardRegexp = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// scrubSecrets masks the substrings of s which look like secrets: JWTs, AWS access keys,
+// bearer tokens, PEM blocks and credit card numbers.
+func (e *Encoder) scrubSecrets(s string) string {
+	mask := e.Mask
+	if mask == "" {
+		mask = DefaultMask
+	}
+	for _, re := range []*regexp.Regexp{pemRegexp, jwtRegexp, bearerRegexp, awsKeyRegexp} {
+		s = re.ReplaceAllString(s, mask)
+	}
+	return creditCardRegexp.ReplaceAllStringFunc(s, func(number string) string {
+		if !luhn(number) {
+			return number
+		}
+		return mask
+	})
+}
+
+// luhn checks the digits of a credit card number with the Luhn algorithm
+func luhn(number string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(number)
+	var sum int
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
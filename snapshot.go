@@ -0,0 +1,140 @@
+package dump
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotFormat identifies the files written by SaveSnapshot
+const SnapshotFormat = "go-dump/snapshot"
+
+// SnapshotVersion is the version of the snapshot files written by SaveSnapshot
+const SnapshotVersion = 1
+
+// ErrInvalidSnapshot is returned when a file is not a snapshot, or when its version is not
+// supported
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// Snapshot is a dump saved with SaveSnapshot. The header describes how the keys have been built,
+// so that snapshots taken at different times can be compared offline.
+type Snapshot struct {
+	Format  string            `json:"format"`
+	Version int               `json:"version"`
+	Time    time.Time         `json:"time"`
+	Type    string            `json:"type"`
+	Options SnapshotOptions   `json:"options"`
+	Values  map[string]string `json:"values"`
+}
+
+// SnapshotOptions are the options of the encoder which shape the keys of a snapshot
+type SnapshotOptions struct {
+	Separator         string         `json:"separator"`
+	Prefix            string         `json:"prefix,omitempty"`
+	Suffix            string         `json:"suffix,omitempty"`
+	RootName          string         `json:"rootName,omitempty"`
+	DisableTypePrefix bool           `json:"disableTypePrefix,omitempty"`
+	TypePrefix        TypePrefixMode `json:"typePrefix,omitempty"`
+	ArrayJSONNotation bool           `json:"arrayJSONNotation,omitempty"`
+}
+
+// SaveSnapshot dumps i with the options and writes it to a snapshot file. See
+// Encoder.SaveSnapshot.
+func SaveSnapshot(path string, i interface{}, opts ...Option) error {
+	return NewDefaultEncoder(opts...).SaveSnapshot(path, i)
+}
+
+// SaveSnapshot dumps i and writes it to a JSON file, with a header holding the format version,
+// the time of the dump, the type of i and the options of the encoder. The file is only readable
+// by its owner and replaced atomically. It is loaded back with LoadSnapshot.
+func (e *Encoder) SaveSnapshot(path string, i interface{}) error {
+	m, err := e.ToStringMap(i)
+	if err != nil {
+		return err
+	}
+	s := Snapshot{
+		Format:  SnapshotFormat,
+		Version: SnapshotVersion,
+		Time:    time.Now().UTC(),
+		Type:    fmt.Sprintf("%T", i),
+		Options: SnapshotOptions{
+			Separator:         e.Separator,
+			Prefix:            e.Prefix,
+			Suffix:            e.Suffix,
+			RootName:          e.RootName,
+			DisableTypePrefix: e.DisableTypePrefix,
+			TypePrefix:        e.TypePrefix,
+			ArrayJSONNotation: e.ArrayJSONNotation,
+		},
+		Values: m,
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(b, '\n'))
+}
+
+// writeFileAtomic writes data to a temporary file, only readable by its owner as the dump may hold
+// credentials, then renames it to path so that the file is never partially written
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LoadSnapshot reads a snapshot file written by SaveSnapshot and returns the dumped values. Use
+// ReadSnapshot to get the header as well.
+func LoadSnapshot(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := ReadSnapshot(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s.Values, nil
+}
+
+// ReadSnapshot decodes a snapshot written by SaveSnapshot. An error wrapping ErrInvalidSnapshot
+// is returned if it is not a snapshot or if its version is newer than SnapshotVersion.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if s.Format != SnapshotFormat {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidSnapshot, s.Format)
+	}
+	if s.Version < 1 || s.Version > SnapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, s.Version)
+	}
+	if s.Values == nil {
+		s.Values = map[string]string{}
+	}
+	return &s, nil
+}
@@ -0,0 +1,62 @@
+package dump_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestSnapshot(t *testing.T) {
+	type T struct {
+		A int
+		B []string
+	}
+	a := T{A: 23, B: []string{"foo", "bar"}}
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	require.NoError(t, dump.SaveSnapshot(path, a, dump.WithPrefix("app")))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	m, err := dump.LoadSnapshot(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"app.T.A":    "23",
+		"app.T.B.B0": "foo",
+		"app.T.B.B1": "bar",
+	}, m)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	s, err := dump.ReadSnapshot(f)
+	require.NoError(t, err)
+	assert.Equal(t, dump.SnapshotVersion, s.Version)
+	assert.Equal(t, "dump_test.T", s.Type)
+	assert.Equal(t, "app", s.Options.Prefix)
+	assert.Equal(t, ".", s.Options.Separator)
+	assert.WithinDuration(t, time.Now(), s.Time, time.Minute)
+}
+
+func TestReadSnapshotInvalid(t *testing.T) {
+	_, err := dump.ReadSnapshot(strings.NewReader(`{"A": "1"}`))
+	assert.True(t, errors.Is(err, dump.ErrInvalidSnapshot))
+
+	_, err = dump.ReadSnapshot(strings.NewReader(`{"format": "go-dump/snapshot", "version": 99}`))
+	assert.True(t, errors.Is(err, dump.ErrInvalidSnapshot))
+
+	_, err = dump.ReadSnapshot(strings.NewReader(`not json`))
+	assert.True(t, errors.Is(err, dump.ErrInvalidSnapshot))
+}
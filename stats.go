@@ -0,0 +1,40 @@
+package dump
+
+import "reflect"
+
+// Stats describes the dump of a value
+type Stats struct {
+	// Keys is the number of dumped keys
+	Keys int
+	// MaxDepth is the depth of the deepest leaf, type names don't count
+	MaxDepth int
+	// Kinds is the number of leaves of each kind
+	Kinds map[reflect.Kind]int
+	// Size is an estimate of the number of bytes written by Fdump
+	Size int
+}
+
+// Stats returns the statistics of the dump of i, without storing the dump, to cheaply decide
+// whether a value is worth dumping
+func (e *Encoder) Stats(i interface{}) (s Stats, err error) {
+	if !e.DisableRecover {
+		defer recoverError(&err)
+	}
+	s.Kinds = map[reflect.Kind]int{}
+	w := e.newDumpState()
+	w.emit = func(k string, v interface{}) bool {
+		s.Keys++
+		s.Kinds[reflect.ValueOf(v).Kind()]++
+		// "key: value\n"
+		s.Size += len(k) + len(e.printValue(v)) + 3
+		return true
+	}
+	if err := e.fdumpInterface(w, i, e.rootSegments(i)); err != nil {
+		return Stats{}, err
+	}
+	if w.err != nil {
+		return Stats{}, w.err
+	}
+	s.MaxDepth = w.maxDepth
+	return s, nil
+}
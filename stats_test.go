@@ -0,0 +1,34 @@
+package dump_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestStats(t *testing.T) {
+	type Inner struct {
+		Tags []string
+	}
+	type T struct {
+		A     int
+		B     string
+		Inner Inner
+	}
+	a := T{A: 1, B: "foo", Inner: Inner{Tags: []string{"x", "y"}}}
+
+	e := dump.NewDefaultEncoder()
+	s, err := e.Stats(a)
+	require.NoError(t, err)
+	assert.Equal(t, 4, s.Keys)
+	assert.Equal(t, 3, s.MaxDepth)
+	assert.Equal(t, map[reflect.Kind]int{reflect.Int: 1, reflect.String: 3}, s.Kinds)
+
+	out, err := e.Sdump(a)
+	require.NoError(t, err)
+	assert.Equal(t, len(out), s.Size)
+}
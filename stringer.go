@@ -0,0 +1,118 @@
+package dump
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// stringer returns the value as a fmt.Stringer, unless DisableStringer is set
+func (e *Encoder) stringer(i interface{}) (fmt.Stringer, bool) {
+	if e.DisableStringer {
+		return nil, false
+	}
+	s, ok := i.(fmt.Stringer)
+	return s, ok
+}
+
+// printWithoutStringer renders a leaf value from its kind, without calling its String method
+func printWithoutStringer(i interface{}) string {
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.String:
+		return v.String()
+	}
+	if b, err := safeMarshalJSON(i); err == nil {
+		return string(b)
+	}
+	return fmt.Sprintf("<%T>", i)
+}
+
+// preferredStringer returns the value as a fmt.Stringer if it must be dumped as a single leaf
+func (e *Encoder) preferredStringer(i interface{}) (fmt.Stringer, bool) {
+	if !e.PreferStringer {
+		return nil, false
+	}
+	return e.stringer(i)
+}
+
+// safeString calls the String method, a panic is rendered as <panic in String(): ...> so that it
+// doesn't abort the dump
+func safeString(s fmt.Stringer) (res string) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = fmt.Sprintf("<panic in String(): %v>", r)
+		}
+	}()
+	return s.String()
+}
+
+// safeMarshalJSON calls json.Marshal, a panic of a MarshalJSON or MarshalText method is rendered
+// as <panic in MarshalJSON(): ...>
+func safeMarshalJSON(i interface{}) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			method := "MarshalJSON"
+			if _, ok := i.(json.Marshaler); !ok {
+				if _, ok := i.(encoding.TextMarshaler); ok {
+					method = "MarshalText"
+				}
+			}
+			b, err = []byte(fmt.Sprintf("<panic in %s(): %v>", method, r)), nil
+		}
+	}()
+	return json.Marshal(i)
+}
+
+// safeMarshalText calls the MarshalText method, a panic is rendered as <panic in MarshalText(): ...>
+func safeMarshalText(m encoding.TextMarshaler) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			b, err = []byte(fmt.Sprintf("<panic in MarshalText(): %v>", r)), nil
+		}
+	}()
+	return m.MarshalText()
+}
+
+// mapKey renders a map key as a key segment. Stringers and TextMarshalers are used if implemented,
+// structs, arrays and pointers are rendered as printValue does.
+func (e *Encoder) mapKey(k reflect.Value) string {
+	if k.Kind() == reflect.Interface {
+		if k.IsNil() {
+			return ""
+		}
+		k = k.Elem()
+	}
+	i := k.Interface()
+	if s, ok := e.stringer(i); ok {
+		return safeString(s)
+	}
+	if m, ok := i.(encoding.TextMarshaler); ok {
+		if b, err := safeMarshalText(m); err == nil {
+			return string(b)
+		}
+	}
+	switch k.Kind() {
+	case reflect.Ptr:
+		if k.IsNil() {
+			return ""
+		}
+		return e.mapKey(k.Elem())
+	case reflect.Struct, reflect.Array:
+		return printValue(i)
+	}
+	if e.DisableStringer {
+		return printWithoutStringer(i)
+	}
+	return fmt.Sprintf("%v", i)
+}
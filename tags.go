@@ -0,0 +1,192 @@
+package dump
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// dumpTag is the parsed value of a `dump:"..."` struct tag, such as `dump:"name,mask=last4"`.
+// The "-", "inline", "mask" and "mask=..." values are options, any other first value is the key name.
+type dumpTag struct {
+	name   string
+	omit   bool
+	inline bool
+	masked bool
+	mask   string
+}
+
+func parseDumpTag(tag string) dumpTag {
+	var t dumpTag
+	if tag == "-" {
+		t.omit = true
+		return t
+	}
+	for i, value := range strings.Split(tag, ",") {
+		switch {
+		case value == "inline":
+			t.inline = true
+		case value == "mask":
+			t.masked = true
+		case strings.HasPrefix(value, "mask="):
+			t.masked = true
+			t.mask = strings.TrimPrefix(value, "mask=")
+		case i == 0:
+			t.name = value
+		}
+	}
+	return t
+}
+
+// DefaultMask is the default string replacing masked values
+const DefaultMask = "***"
+
+// maskValue masks a value according to the mask option of the dump tag: an empty mask
+// replaces the whole value, "lastN" and "firstN" keep respectively the last and first N
+// characters of the value.
+func (e *Encoder) maskValue(i interface{}, mask string) interface{} {
+	s := printValue(i)
+	if s == "" {
+		return ""
+	}
+	m := e.Mask
+	if m == "" {
+		m = DefaultMask
+	}
+	runes := []rune(s)
+	var n int
+	switch {
+	case strings.HasPrefix(mask, "last"):
+		if _, err := fmt.Sscanf(mask, "last%d", &n); err == nil && n < len(runes) {
+			return m + string(runes[len(runes)-n:])
+		}
+	case strings.HasPrefix(mask, "first"):
+		if _, err := fmt.Sscanf(mask, "first%d", &n); err == nil && n < len(runes) {
+			return string(runes[:n]) + m
+		}
+	}
+	return m
+}
+
+// keyTags returns the struct tags used, in order, to compute the key segment of the fields.
+// The dump tag is not part of them since it is handled separately.
+func (e *Encoder) keyTags() []string {
+	var tags []string
+	if e.TagPriority != nil {
+		for _, tag := range e.TagPriority {
+			if tag != "dump" {
+				tags = append(tags, tag)
+			}
+		}
+		return tags
+	}
+	if e.KeyTag != "" && e.KeyTag != "dump" {
+		tags = append(tags, e.KeyTag)
+	}
+	if e.ExtraFields.UseJSONTag {
+		tags = append(tags, "json")
+	}
+	if e.ExtraFields.UseMapstructureTag {
+		tags = append(tags, "mapstructure")
+	}
+	if e.ExtraFields.UseYAMLTag {
+		tags = append(tags, "yaml")
+	}
+	return tags
+}
+
+// parseKeyTag splits a json-like struct tag value into the key name and its options
+func parseKeyTag(tag string) (string, []string) {
+	values := strings.Split(tag, ",")
+	return values[0], values[1:]
+}
+
+// fieldOmission returns whether the struct field is never dumped, because of its `dump:"-"` tag,
+// or only when its value is empty, from the "-" and "omitempty" values of the tags returned by
+// keyTags
+func (e *Encoder) fieldOmission(field reflect.StructField) (always, ifEmpty bool) {
+	if parseDumpTag(field.Tag.Get("dump")).omit {
+		return true, false
+	}
+	for _, key := range e.keyTags() {
+		tag := field.Tag.Get(key)
+		if tag == "-" {
+			return true, false
+		}
+		_, opts := parseKeyTag(tag)
+		for _, opt := range opts {
+			if opt == "omitempty" {
+				ifEmpty = true
+			}
+		}
+	}
+	return false, ifEmpty
+}
+
+// inlineField returns true if the fields of the struct field must be dumped under its parent,
+// because of its `dump:"inline"` tag, the "squash" option of the mapstructure tag or the
+// "inline" option of the yaml tag.
+func (e *Encoder) inlineField(field reflect.StructField) bool {
+	if parseDumpTag(field.Tag.Get("dump")).inline {
+		return true
+	}
+	for _, key := range e.keyTags() {
+		_, opts := parseKeyTag(field.Tag.Get(key))
+		for _, opt := range opts {
+			if opt == "squash" || opt == "inline" {
+				return true
+			}
+		}
+	}
+	// As with encoding/json, embedded structs with a tag name are not promoted
+	return e.PromoteEmbedded && field.Anonymous && e.tagName(field) == ""
+}
+
+// isEmptyValue reports whether v is empty, as defined by the omitempty option of encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// fieldName computes the key segment of a struct field. The tags are checked in the order of
+// TagPriority if set, otherwise the `dump` tag takes precedence over the tags returned by
+// keyTags. The field name is used as a fallback.
+func (e *Encoder) fieldName(field reflect.StructField) string {
+	if name := e.tagName(field); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// tagName returns the name of the struct field given by its tags, or an empty string
+func (e *Encoder) tagName(field reflect.StructField) string {
+	tags := e.TagPriority
+	if tags == nil {
+		tags = append([]string{"dump"}, e.keyTags()...)
+	}
+	for _, key := range tags {
+		var name string
+		if key == "dump" {
+			name = parseDumpTag(field.Tag.Get(key)).name
+		} else {
+			name, _ = parseKeyTag(field.Tag.Get(key))
+		}
+		if name != "" && name != "omitempty" {
+			return name
+		}
+	}
+	return ""
+}
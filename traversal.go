@@ -0,0 +1,83 @@
+package dump
+
+import "sort"
+
+// Traversal is the order in which the leaves are dumped
+type Traversal int
+
+const (
+	// TraversalDepthFirst dumps each value with all its descendants before its next sibling
+	TraversalDepthFirst Traversal = iota
+	// TraversalBreadthFirst dumps the shallow leaves before the deeper ones: the leaves are yielded by
+	// All and written by Fdump and Sdump by increasing depth. The value is walked once and its leaves
+	// are buffered until the walk is over.
+	TraversalBreadthFirst
+)
+
+// breadthFirst dumps i and emits its leaves by increasing depth, with their level. The leaves of
+// the same level are emitted in the order they have been dumped.
+func (e *Encoder) breadthFirst(i interface{}, emit func(k string, v interface{}, level int) bool) error {
+	type entry struct {
+		k     string
+		v     interface{}
+		level int
+	}
+	var entries []entry
+	w := e.newDumpState()
+	w.trackDepth = true
+	w.emit = func(k string, v interface{}) bool {
+		entries = append(entries, entry{k: k, v: v, level: max(w.depth, 1)})
+		return true
+	}
+	if err := e.fdumpInterface(w, i, e.rootSegments(i)); err != nil {
+		return err
+	}
+	if w.err != nil {
+		return w.err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].level < entries[j].level
+	})
+	for _, en := range entries {
+		if !emit(en.k, en.v, en.level) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// toStringMap is like ToStringMap, with the level of each key for TraversalBreadthFirst
+func (e *Encoder) toStringMap(i interface{}) (res map[string]string, levels map[string]int, err error) {
+	if e.Traversal != TraversalBreadthFirst {
+		res, err = e.ToStringMap(i)
+		return res, nil, err
+	}
+	if !e.DisableRecover {
+		defer recoverError(&err)
+	}
+	values := map[string]interface{}{}
+	valueLevels := map[string]int{}
+	err = e.breadthFirst(i, func(k string, v interface{}, level int) bool {
+		values[k] = v
+		valueLevels[k] = level
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.PostProcess != nil {
+		values = e.PostProcess(values)
+	}
+
+	res = map[string]string{}
+	levels = map[string]int{}
+	for k, v := range values {
+		lines := map[string]string{}
+		e.multiline(lines, k, e.printValue(v))
+		for lk, lv := range lines {
+			res[lk] = lv
+			levels[lk] = valueLevels[k]
+		}
+	}
+	return res, levels, nil
+}
@@ -0,0 +1,401 @@
+package dump
+
+import (
+	"container/list"
+	"container/ring"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DumperFunc renders a value as a single leaf
+type DumperFunc func(v reflect.Value) interface{}
+
+// RegisterDumper registers a function used to render all the values of type t as a single leaf
+func (e *Encoder) RegisterDumper(t reflect.Type, fn DumperFunc) {
+	if e.dumpers == nil {
+		e.dumpers = map[reflect.Type]DumperFunc{}
+	}
+	e.dumpers[t] = fn
+}
+
+// interfaceDumper renders the values implementing an interface
+type interfaceDumper struct {
+	iface reflect.Type
+	fn    DumperFunc
+}
+
+// RegisterInterfaceDumper registers a function used to render all the values implementing the
+// interface type iface, such as driver.Valuer or error, as a single leaf. The function receives
+// the value implementing the interface, which may be a pointer to the dumped value. Dumpers
+// registered for an exact type with RegisterDumper take precedence, the interface dumpers are
+// tried in the order they are registered. It panics if iface is not an interface type.
+func (e *Encoder) RegisterInterfaceDumper(iface reflect.Type, fn DumperFunc) {
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("dump: RegisterInterfaceDumper of non-interface type %v", iface))
+	}
+	e.interfaceDumpers = append(e.interfaceDumpers, interfaceDumper{iface: iface, fn: fn})
+}
+
+// interfaceDumper returns the result of the first interface dumper matching f
+func (e *Encoder) interfaceDumper(f reflect.Value) (interface{}, bool) {
+	for _, d := range e.interfaceDumpers {
+		if f.Type().Implements(d.iface) {
+			return d.fn(f), true
+		}
+		if reflect.PointerTo(f.Type()).Implements(d.iface) {
+			if !f.CanAddr() {
+				c := reflect.New(f.Type()).Elem()
+				c.Set(f)
+				f = c
+			}
+			return d.fn(f.Addr()), true
+		}
+	}
+	return nil, false
+}
+
+var defaultSkipTypes = []reflect.Type{
+	reflect.TypeOf(sync.Mutex{}),
+	reflect.TypeOf(sync.RWMutex{}),
+	reflect.TypeOf(sync.Once{}),
+}
+
+// SkipTypes registers types whose values are omitted from the dump. Pointers to those
+// types are omitted as well. sync.Mutex, sync.RWMutex and sync.Once are skipped by default.
+func (e *Encoder) SkipTypes(types ...reflect.Type) {
+	if e.skipTypes == nil {
+		e.skipTypes = map[reflect.Type]bool{}
+	}
+	for _, t := range types {
+		e.skipTypes[t] = true
+	}
+}
+
+func (e *Encoder) skipped(i interface{}) bool {
+	if len(e.skipTypes) == 0 || i == nil {
+		return false
+	}
+	t := reflect.TypeOf(i)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return e.skipTypes[t]
+}
+
+// OpaqueTypes registers types whose values are not walked through but rendered as a single
+// leaf, with their String method if any. Interface types, such as context.Context, match
+// all the values implementing them.
+func (e *Encoder) OpaqueTypes(types ...reflect.Type) {
+	e.opaques = append(e.opaques, types...)
+}
+
+func (e *Encoder) opaque(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	for _, o := range e.opaques {
+		if t == o || (t.Kind() == reflect.Ptr && t.Elem() == o) {
+			return true
+		}
+		if o.Kind() == reflect.Interface && t.Implements(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// UUIDDumper renders a [16]byte array, such as uuid.UUID, as a canonical UUID string
+func UUIDDumper(v reflect.Value) interface{} {
+	b := make([]byte, v.Len())
+	for i := range b {
+		b[i] = byte(v.Index(i).Uint())
+	}
+	if len(b) != 16 {
+		return hex.EncodeToString(b)
+	}
+	return hex.EncodeToString(b[0:4]) + "-" +
+		hex.EncodeToString(b[4:6]) + "-" +
+		hex.EncodeToString(b[6:8]) + "-" +
+		hex.EncodeToString(b[8:10]) + "-" +
+		hex.EncodeToString(b[10:16])
+}
+
+// DurationFormat is the way time.Duration values are rendered
+type DurationFormat int
+
+const (
+	// DurationDefault keeps time.Duration values as they are
+	DurationDefault DurationFormat = iota
+	// DurationString renders durations with time.Duration.String, such as 1h30m0s
+	DurationString
+	// DurationSeconds renders durations as a floating number of seconds
+	DurationSeconds
+	// DurationNanoseconds renders durations as an integer number of nanoseconds
+	DurationNanoseconds
+)
+
+// UnixTimeFormat is the unit used to render time.Time values as Unix timestamps
+type UnixTimeFormat int
+
+const (
+	// UnixTimeDisabled doesn't render time.Time values as Unix timestamps
+	UnixTimeDisabled UnixTimeFormat = iota
+	// UnixTimeSeconds renders time.Time values as a number of seconds since the Unix epoch
+	UnixTimeSeconds
+	// UnixTimeMilliseconds renders time.Time values as a number of milliseconds since the Unix epoch
+	UnixTimeMilliseconds
+)
+
+// BoolFormat is the pair of strings used to render bool values
+type BoolFormat struct {
+	True  string
+	False string
+}
+
+var (
+	// BoolYesNo renders bool values as yes or no
+	BoolYesNo = BoolFormat{True: "yes", False: "no"}
+	// BoolOneZero renders bool values as 1 or 0
+	BoolOneZero = BoolFormat{True: "1", False: "0"}
+	// BoolEnabledDisabled renders bool values as enabled or disabled
+	BoolEnabledDisabled = BoolFormat{True: "enabled", False: "disabled"}
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// leafValue returns the value to dump for the types which are rendered as a
+// single leaf instead of being walked through.
+func (e *Encoder) leafValue(f reflect.Value) (interface{}, bool) {
+	if !f.IsValid() || !f.CanInterface() {
+		return nil, false
+	}
+	if fn, ok := e.dumpers[f.Type()]; ok {
+		return fn(f), true
+	}
+	if v, ok := e.interfaceDumper(f); ok {
+		return v, true
+	}
+	if e.DetectUUID && isUUIDArray(f.Type()) {
+		return UUIDDumper(f), true
+	}
+	switch f.Type() {
+	case timeType:
+		t := f.Interface().(time.Time)
+		switch e.UnixTime {
+		case UnixTimeSeconds:
+			return t.Unix(), true
+		case UnixTimeMilliseconds:
+			return t.UnixNano() / int64(time.Millisecond), true
+		}
+		if e.TimeFormat == "" && !e.TimeUTC {
+			return nil, false
+		}
+		if e.TimeUTC {
+			t = t.UTC()
+		}
+		if e.TimeFormat == "" {
+			return t.String(), true
+		}
+		return t.Format(e.TimeFormat), true
+	case durationType:
+		d := time.Duration(f.Int())
+		switch e.DurationFormat {
+		case DurationString:
+			return d.String(), true
+		case DurationSeconds:
+			return d.Seconds(), true
+		case DurationNanoseconds:
+			return d.Nanoseconds(), true
+		}
+	case bigIntType:
+		i := f.Interface().(big.Int)
+		return i.String(), true
+	case bigFloatType:
+		fl := f.Interface().(big.Float)
+		switch {
+		case e.BigFloatPrecision > 0:
+			return fl.Text('g', e.BigFloatPrecision), true
+		case e.BigFloatPrecision < 0:
+			return fl.Text('g', -1), true
+		}
+		return fl.String(), true
+	case bigRatType:
+		r := f.Interface().(big.Rat)
+		return r.String(), true
+	}
+	if f.Kind() == reflect.Bool && e.BoolFormat != (BoolFormat{}) {
+		if f.Bool() {
+			return e.BoolFormat.True, true
+		}
+		return e.BoolFormat.False, true
+	}
+	return nil, false
+}
+
+func isUUIDArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// isLeaf returns true if the value is not walked through but dumped as a single leaf
+func (e *Encoder) isLeaf(f reflect.Value) bool {
+	if _, ok := e.leafValue(f); ok {
+		return true
+	}
+	return f.IsValid() && (isSQLNull(f.Type()) || e.opaque(f.Type()))
+}
+
+// isSQLNull returns true for the database/sql Null* types, such as sql.NullString
+// or sql.NullTime, which all hold the value in their first field and a Valid flag.
+func isSQLNull(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.PkgPath() != "database/sql" || !strings.HasPrefix(t.Name(), "Null") {
+		return false
+	}
+	valid, ok := t.FieldByName("Valid")
+	return ok && valid.Type.Kind() == reflect.Bool && t.NumField() == 2
+}
+
+// sqlNullValue returns the value held by a database/sql Null* type, or nil if it is not valid
+func sqlNullValue(f reflect.Value) interface{} {
+	if !f.FieldByName("Valid").Bool() {
+		return nil
+	}
+	return f.Field(0).Interface()
+}
+
+var (
+	listType = reflect.TypeOf(list.List{})
+	ringType = reflect.TypeOf(ring.Ring{})
+)
+
+// containerElements returns the values of the elements of the container/list and container/ring
+// values, so that they are dumped as arrays instead of walking their pointers.
+func containerElements(f reflect.Value) ([]interface{}, bool) {
+	switch f.Type() {
+	case listType:
+		l := f.Interface().(list.List)
+		elems := []interface{}{}
+		// The elements reference the original list, so they can be walked from the copy
+		for el := l.Front(); el != nil; el = el.Next() {
+			elems = append(elems, el.Value)
+		}
+		return elems, true
+	case ringType:
+		if !f.CanAddr() {
+			return nil, false
+		}
+		elems := []interface{}{}
+		f.Addr().Interface().(*ring.Ring).Do(func(v interface{}) {
+			elems = append(elems, v)
+		})
+		return elems, true
+	}
+	return nil, false
+}
+
+// DefaultMaxSeqElements is the default maximum number of elements dumped from a sequence
+const DefaultMaxSeqElements = 1000
+
+var boolType = reflect.TypeOf(true)
+
+// seqPair is a key and value yielded by an iter.Seq2
+type seqPair struct {
+	key, value reflect.Value
+}
+
+// isSeqType tells if t is an instance of iter.Seq or iter.Seq2, the other funcs are never called
+func isSeqType(t reflect.Type) bool {
+	return t.PkgPath() == "iter" && (strings.HasPrefix(t.Name(), "Seq[") || strings.HasPrefix(t.Name(), "Seq2["))
+}
+
+// seqElements returns the elements yielded by iter.Seq and iter.Seq2 values. iter.Seq elements
+// are returned as a slice and iter.Seq2 ones as a []seqPair in yield order, so that repeated keys
+// are kept. At most Limits.MaxSeqElements elements are returned.
+func (e *Encoder) seqElements(f reflect.Value) (interface{}, bool) {
+	t := f.Type()
+	if t.Kind() != reflect.Func || f.IsNil() || !isSeqType(t) || t.NumIn() != 1 || t.NumOut() != 0 {
+		return nil, false
+	}
+	yield := t.In(0)
+	if yield.Kind() != reflect.Func || yield.NumOut() != 1 || yield.Out(0) != boolType || yield.NumIn() < 1 || yield.NumIn() > 2 {
+		return nil, false
+	}
+	max := e.Limits.MaxSeqElements
+	if max <= 0 {
+		max = DefaultMaxSeqElements
+	}
+
+	var n int
+	var elems []interface{}
+	pairs := []seqPair{}
+	fn := reflect.MakeFunc(yield, func(args []reflect.Value) []reflect.Value {
+		if len(args) == 1 {
+			elems = append(elems, args[0].Interface())
+		} else {
+			pairs = append(pairs, seqPair{key: args[0], value: args[1]})
+		}
+		n++
+		return []reflect.Value{reflect.ValueOf(n < max)}
+	})
+	f.Call([]reflect.Value{fn})
+	if yield.NumIn() == 2 {
+		return pairs, true
+	}
+	if elems == nil {
+		elems = []interface{}{}
+	}
+	return elems, true
+}
+
+// EmptyMapKeysMode defines how the map entries whose key is rendered as an empty string are handled
+type EmptyMapKeysMode int
+
+const (
+	// EmptyMapKeysSkip doesn't dump the entries with an empty key
+	EmptyMapKeysSkip EmptyMapKeysMode = iota
+	// EmptyMapKeysPlaceholder dumps the entries with an empty key under EmptyMapKeyPlaceholder
+	EmptyMapKeysPlaceholder
+	// EmptyMapKeysError returns an error wrapping ErrEmptyMapKey
+	EmptyMapKeysError
+)
+
+// EmptyMapKeyPlaceholder is the key segment of the map entries with an empty key with
+// EmptyMapKeysPlaceholder
+const EmptyMapKeyPlaceholder = "<empty>"
+
+// TypePrefixMode defines which struct values have their type name inserted in the keys
+type TypePrefixMode int
+
+const (
+	// TypePrefixRootAndMapValues inserts the type name of the dumped struct and of the struct
+	// values of maps
+	TypePrefixRootAndMapValues TypePrefixMode = iota
+	// TypePrefixNever never inserts the type names
+	TypePrefixNever
+	// TypePrefixRootOnly only inserts the type name of the dumped struct
+	TypePrefixRootOnly
+	// TypePrefixAlways inserts the type name of every struct value, including the struct fields
+	// and the elements of arrays and slices
+	TypePrefixAlways
+	// TypePrefixMapValuesOnly only inserts the type name of the struct values of maps
+	TypePrefixMapValuesOnly
+)
+
+// typePosition is the position of a struct value in the dumped value
+type typePosition int
+
+const (
+	rootPosition typePosition = iota
+	mapValuePosition
+	nestedPosition
+)
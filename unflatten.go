@@ -0,0 +1,197 @@
+package dump
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// unflattenNode is a node of the tree of the keys given to Unflatten
+type unflattenNode struct {
+	value  *string
+	fields map[string]*unflattenNode
+	// elems are the elements addressed with the JSON notation, such as b[0]
+	elems map[int]*unflattenNode
+}
+
+func (n *unflattenNode) leaf() bool {
+	return n.value != nil
+}
+
+func (n *unflattenNode) field(name string) *unflattenNode {
+	if n.fields == nil {
+		n.fields = map[string]*unflattenNode{}
+	}
+	c, ok := n.fields[name]
+	if !ok {
+		c = &unflattenNode{}
+		n.fields[name] = c
+	}
+	return c
+}
+
+func (n *unflattenNode) elem(i int) *unflattenNode {
+	if n.elems == nil {
+		n.elems = map[int]*unflattenNode{}
+	}
+	c, ok := n.elems[i]
+	if !ok {
+		c = &unflattenNode{}
+		n.elems[i] = c
+	}
+	return c
+}
+
+// jsonIndexes matches the array indexes of the JSON notation, such as b[0][1]
+var jsonIndexes = regexp.MustCompile(`^(.*?)((?:\[\d+\])+)$`)
+
+// Unflatten rebuilds the nested maps and slices of a flattened map, such as the result of
+// ToStringMap, whose keys are separated with dots. See Encoder.Unflatten.
+func Unflatten(m map[string]string) (map[string]interface{}, error) {
+	return NewDefaultEncoder().Unflatten(m)
+}
+
+// Unflatten rebuilds the nested maps and slices of a flattened map, whose keys are separated with
+// the Separator of the encoder. The leaves are strings.
+//
+// Both array notations are parsed: the elements of b are either keyed b[0] or b.b0. As a flattened
+// map doesn't tell the arrays from the maps, the keys of b are only read as the elements of an
+// array if all of them are made of the name b followed by the indexes 0 to n-1: the key http.http2
+// alone is a map, while http.http0 alone is an array. The indexes can't exceed the number of keys.
+//
+// An error wrapping ErrKeyCollision is returned if a key is both a leaf and the parent of other
+// keys, or if it is both a map and an array, and an error wrapping ErrInvalidIndex if an index
+// can't be parsed or is out of range.
+func (e *Encoder) Unflatten(m map[string]string) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	root := &unflattenNode{}
+	for _, k := range keys {
+		n, err := e.unflattenKey(root, k, len(m))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, k)
+		}
+		if n.leaf() || n.fields != nil || n.elems != nil {
+			return nil, fmt.Errorf("%w: %s", ErrKeyCollision, k)
+		}
+		v := m[k]
+		n.value = &v
+	}
+	if root.elems != nil {
+		return nil, fmt.Errorf("%w: the root is an array", ErrKeyCollision)
+	}
+	res := map[string]interface{}{}
+	for name, c := range root.fields {
+		v, err := c.interfaceValue(name)
+		if err != nil {
+			return nil, err
+		}
+		res[name] = v
+	}
+	return res, nil
+}
+
+// unflattenKey returns the node of the key k, created with its parents. The indexes of the JSON
+// notation must be lower than max.
+func (e *Encoder) unflattenKey(root *unflattenNode, k string, max int) (*unflattenNode, error) {
+	segments := []string{k}
+	if e.Separator != "" {
+		segments = strings.Split(k, e.Separator)
+	}
+	n := root
+	for _, s := range segments {
+		var indexes []string
+		if m := jsonIndexes.FindStringSubmatch(s); m != nil {
+			s = m[1]
+			indexes = strings.Split(strings.Trim(m[2], "[]"), "][")
+		}
+		if s != "" || indexes == nil {
+			if n.leaf() || n.elems != nil {
+				return nil, ErrKeyCollision
+			}
+			n = n.field(s)
+		}
+		for _, idx := range indexes {
+			i, err := strconv.Atoi(idx)
+			if err != nil || i >= max {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidIndex, idx)
+			}
+			if n.leaf() || n.fields != nil {
+				return nil, ErrKeyCollision
+			}
+			n = n.elem(i)
+		}
+	}
+	return n, nil
+}
+
+// interfaceValue returns the value of the node whose key segment is name
+func (n *unflattenNode) interfaceValue(name string) (interface{}, error) {
+	if n.leaf() {
+		return *n.value, nil
+	}
+	if n.elems != nil {
+		last := -1
+		for i := range n.elems {
+			if i > last {
+				last = i
+			}
+		}
+		res := make([]interface{}, last+1)
+		for i, c := range n.elems {
+			v, err := c.interfaceValue(name)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+		}
+		return res, nil
+	}
+	if elems, ok := n.indexedFields(name); ok {
+		res := make([]interface{}, len(elems))
+		for i, c := range elems {
+			v, err := c.interfaceValue(fmt.Sprintf("%s%d", name, i))
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+		}
+		return res, nil
+	}
+	res := make(map[string]interface{}, len(n.fields))
+	for k, c := range n.fields {
+		v, err := c.interfaceValue(k)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = v
+	}
+	return res, nil
+}
+
+// indexedFields returns the fields of the node in index order if they are all named name0 to
+// nameN, the elements of an array in the default notation
+func (n *unflattenNode) indexedFields(name string) ([]*unflattenNode, bool) {
+	if name == "" {
+		return nil, false
+	}
+	elems := make([]*unflattenNode, len(n.fields))
+	for k, c := range n.fields {
+		idx, ok := strings.CutPrefix(k, name)
+		if !ok {
+			return nil, false
+		}
+		i, err := strconv.Atoi(idx)
+		if err != nil || i < 0 || i >= len(elems) || strconv.Itoa(i) != idx {
+			return nil, false
+		}
+		elems[i] = c
+	}
+	return elems, true
+}
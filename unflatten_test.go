@@ -0,0 +1,106 @@
+package dump_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestUnflatten(t *testing.T) {
+	expected := map[string]interface{}{
+		"T": map[string]interface{}{
+			"Name": "foo",
+			"Tags": []interface{}{"a", "b"},
+			"Servers": []interface{}{
+				map[string]interface{}{"Host": "h1"},
+				map[string]interface{}{"Host": "h2"},
+			},
+			"Labels": map[string]interface{}{"env": "prod"},
+		},
+	}
+
+	m, err := dump.Unflatten(map[string]string{
+		"T.Name":                  "foo",
+		"T.Tags.Tags0":            "a",
+		"T.Tags.Tags1":            "b",
+		"T.Servers.Servers0.Host": "h1",
+		"T.Servers.Servers1.Host": "h2",
+		"T.Labels.env":            "prod",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, m)
+
+	m, err = dump.Unflatten(map[string]string{
+		"T.Name":            "foo",
+		"T.Tags[0]":         "a",
+		"T.Tags[1]":         "b",
+		"T.Servers[0].Host": "h1",
+		"T.Servers[1].Host": "h2",
+		"T.Labels.env":      "prod",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, m)
+
+	m, err = dump.Unflatten(map[string]string{"Grid[1][0]": "x", "Name": "grid"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"Grid": []interface{}{nil, []interface{}{"x"}}, "Name": "grid"}, m)
+
+	_, err = dump.Unflatten(map[string]string{"a": "1", "a.b": "2"})
+	assert.True(t, errors.Is(err, dump.ErrKeyCollision))
+}
+
+func TestUnflattenAmbiguousKeys(t *testing.T) {
+	m, err := dump.Unflatten(map[string]string{
+		"http.http2":    "enabled",
+		"ports.ports0":  "80",
+		"ports.ports1":  "443",
+		"hosts.hosts1":  "b",
+		"hosts.hosts01": "c",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"http":  map[string]interface{}{"http2": "enabled"},
+		"ports": []interface{}{"80", "443"},
+		"hosts": map[string]interface{}{"hosts1": "b", "hosts01": "c"},
+	}, m)
+}
+
+func TestUnflattenInvalidIndex(t *testing.T) {
+	for _, k := range []string{"Tags[99999999999999999999]", "Tags[999999999]", "Tags[1]"} {
+		_, err := dump.Unflatten(map[string]string{k: "x"})
+		assert.True(t, errors.Is(err, dump.ErrInvalidIndex), k)
+	}
+
+	m, err := dump.Unflatten(map[string]string{"Tags.Tags99999999999999999999": "x"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"Tags": map[string]interface{}{"Tags99999999999999999999": "x"}}, m)
+}
+
+func TestUnflattenToStringMap(t *testing.T) {
+	type Server struct {
+		Host string
+	}
+	type T struct {
+		Servers []Server
+	}
+	a := T{Servers: []Server{{"h1"}, {"h2"}}}
+
+	e := dump.NewDefaultEncoder()
+	e.Separator = "/"
+	flat, err := e.ToStringMap(a)
+	require.NoError(t, err)
+	m, err := e.Unflatten(flat)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"T": map[string]interface{}{
+			"Servers": []interface{}{
+				map[string]interface{}{"Host": "h1"},
+				map[string]interface{}{"Host": "h2"},
+			},
+		},
+	}, m)
+}
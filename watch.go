@@ -0,0 +1,67 @@
+package dump
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Watcher dumps a value on demand or periodically, and calls a callback with the changed keys
+// since the previous dump. The value is usually a pointer, so that its updates are seen.
+type Watcher struct {
+	encoder  *Encoder
+	value    interface{}
+	onChange func(changes []Change)
+
+	mu   sync.Mutex
+	last map[string]string
+}
+
+// NewWatcher returns a Watcher over the value, dumped with the options. The first snapshot is
+// taken immediately.
+func NewWatcher(value interface{}, onChange func(changes []Change), opts ...Option) (*Watcher, error) {
+	w := &Watcher{
+		encoder:  NewDefaultEncoder(opts...),
+		value:    value,
+		onChange: onChange,
+	}
+	last, err := w.encoder.ToStringMap(value)
+	if err != nil {
+		return nil, err
+	}
+	w.last = last
+	return w, nil
+}
+
+// Check dumps the value and compares it with the previous snapshot. If keys changed, the
+// callback is called and the changes are returned.
+func (w *Watcher) Check() ([]Change, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	current, err := w.encoder.ToStringMap(w.value)
+	if err != nil {
+		return nil, err
+	}
+	changes := diffMaps(w.last, current)
+	w.last = current
+	if len(changes) > 0 && w.onChange != nil {
+		w.onChange(changes)
+	}
+	return changes, nil
+}
+
+// Run checks the value at each interval until the context is done or a dump fails
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := w.Check(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package dump_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fsamin/go-dump"
+)
+
+func TestWatcher(t *testing.T) {
+	type Config struct {
+		Level string
+		Port  int
+	}
+	cfg := &Config{Level: "info", Port: 8080}
+
+	var mu sync.Mutex
+	var notified [][]dump.Change
+	w, err := dump.NewWatcher(cfg, func(changes []dump.Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		notified = append(notified, changes)
+	})
+	require.NoError(t, err)
+
+	changes, err := w.Check()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Empty(t, notified)
+
+	cfg.Level = "debug"
+	changes, err = w.Check()
+	require.NoError(t, err)
+	assert.Equal(t, []dump.Change{{Key: "Config.Level", Old: "info", New: "debug"}}, changes)
+	assert.Len(t, notified, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, w.Run(ctx, 5*time.Millisecond))
+	assert.Len(t, notified, 1)
+}
@@ -0,0 +1,29 @@
+package dump
+
+import (
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseYAML parses s as a YAML document, it returns false unless the document is a mapping or
+// a sequence in flow style, such as {"id": 1}, or spanning several lines or holding several
+// entries, so that prose such as "Error: connection refused" is not expanded
+func (e *Encoder) parseYAML(s string) (interface{}, bool) {
+	if !strings.ContainsAny(s, ":-[{") || !e.deepJSONAllowed(s) {
+		return nil, false
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(s), &v); err != nil {
+		return nil, false
+	}
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") || strings.Contains(s, "\n") || reflect.ValueOf(v).Len() > 1 {
+			return v, true
+		}
+	}
+	return nil, false
+}